@@ -0,0 +1,311 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+	"github.com/pawanpaudel93/go-m3u-parser/store"
+)
+
+// keyValuesFlag - A repeatable key=value flag keeping the values of every key in order.
+type keyValuesFlag struct {
+	keys   []string
+	values map[string][]string
+}
+
+func (f *keyValuesFlag) String() string {
+	var pairs []string
+	for _, key := range f.keys {
+		for _, value := range f.values[key] {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, " ")
+}
+
+func (f *keyValuesFlag) Set(pair string) error {
+	i := strings.Index(pair, "=")
+	if i <= 0 {
+		return fmt.Errorf("want key=value, got %q", pair)
+	}
+	key, value := pair[:i], pair[i+1:]
+	if f.values == nil {
+		f.values = make(map[string][]string)
+	}
+	if _, ok := f.values[key]; !ok {
+		f.keys = append(f.keys, key)
+	}
+	f.values[key] = append(f.values[key], value)
+	return nil
+}
+
+// transform - Transforms the loaded playlists into the collection written by a command.
+type transform func(parser *m3uparser.M3uParser) (m3uparser.Collection, error)
+
+// pipe runs a command that loads its inputs, transforms them and writes the result. setup adds
+// the flags of the command and returns the transform run once the flags are parsed.
+func pipe(env *env, args []string, name, defaultFormat string, setup func(flags *flag.FlagSet) transform) error {
+	flags := newFlagSet(env, name, "[input...]")
+	in := addInputFlags(flags)
+	out := addOutputFlags(flags, defaultFormat)
+	run := setup(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	return pipeInputs(env, flags, in, out, run)
+}
+
+// pipeInputs loads the inputs of flags, transforms them with run and writes the result to out.
+func pipeInputs(env *env, flags *flag.FlagSet, in *inputFlags, out *outputFlags, run transform) error {
+	parser, err := in.load(flags.Args(), env.stdin)
+	if err != nil {
+		return err
+	}
+	collection := parser.Collection()
+	if run != nil {
+		if collection, err = run(parser); err != nil {
+			return err
+		}
+	}
+	return out.write(collection, env.stdout)
+}
+
+// parse writes the inputs as JSON.
+func parse(env *env, args []string) error {
+	return pipe(env, args, "parse", "json", func(flags *flag.FlagSet) transform { return nil })
+}
+
+// convert writes the inputs in another format.
+func convert(env *env, args []string) error {
+	return pipe(env, args, "convert", "m3u", func(flags *flag.FlagSet) transform { return nil })
+}
+
+// dedupeOptions returns the options of a strategy name.
+func dedupeOptions(strategy string, merge bool) (m3uparser.DedupeOptions, error) {
+	value, err := m3uparser.ParseDedupeStrategy(strategy)
+	if err != nil {
+		return m3uparser.DedupeOptions{}, usageError(err.Error())
+	}
+	return m3uparser.DedupeOptions{Strategy: value, Merge: merge}, nil
+}
+
+// merge writes the inputs as one playlist, optionally without duplicates.
+func merge(env *env, args []string) error {
+	return pipe(env, args, "merge", "m3u", func(flags *flag.FlagSet) transform {
+		strategy := flags.String("dedupe", "", "remove duplicates by url, normalized-url, tvg-id or title-country")
+		alternatives := flags.Bool("alternatives", false, "keep the urls of removed duplicates as alternatives")
+		return func(parser *m3uparser.M3uParser) (m3uparser.Collection, error) {
+			collection := parser.Collection()
+			if *strategy == "" {
+				return collection, nil
+			}
+			opts, err := dedupeOptions(*strategy, *alternatives)
+			return collection.Dedupe(opts), err
+		}
+	})
+}
+
+// dedupe removes duplicate streams.
+func dedupe(env *env, args []string) error {
+	return pipe(env, args, "dedupe", "m3u", func(flags *flag.FlagSet) transform {
+		strategy := flags.String("by", "url", "duplicates have the same url, normalized-url, tvg-id or title-country")
+		alternatives := flags.Bool("alternatives", false, "keep the urls of removed duplicates as alternatives")
+		return func(parser *m3uparser.M3uParser) (m3uparser.Collection, error) {
+			opts, err := dedupeOptions(*strategy, *alternatives)
+			return parser.Collection().Dedupe(opts), err
+		}
+	})
+}
+
+// filter keeps or removes streams by key path values, a query expression or airing programmes.
+func filter(env *env, args []string) error {
+	return pipe(env, args, "filter", "m3u", func(flags *flag.FlagSet) transform {
+		var by, not keyValuesFlag
+		var guides stringsFlag
+		flags.Var(&by, "by", "keep streams whose `key=value` matches, values of a key are or'ed, can be repeated")
+		flags.Var(&not, "not", "remove streams whose `key=value` matches, can be repeated")
+		where := flags.String("where", "", "keep streams matching a query `expression`, eg. 'status == \"GOOD\"'")
+		mode := flags.String("mode", "contains", "match values by contains, exact, prefix or regex")
+		all := flags.Bool("all", false, "require all values of a key to match")
+		caseSensitive := flags.Bool("case-sensitive", false, "match values case-sensitively")
+		airing := flags.String("airing", "", "keep streams airing a programme matching the regular `expression`")
+		flags.Var(&guides, "guide", "XMLTV guide `source` of -airing, can be repeated (default the guides of the playlist)")
+		within := flags.Duration("within", 0, "with -airing, programmes airing from now until within, 0 for airing now")
+		return func(parser *m3uparser.M3uParser) (m3uparser.Collection, error) {
+			matchMode, err := m3uparser.ParseMatchMode(*mode)
+			if err != nil {
+				return m3uparser.Collection{}, usageError(err.Error())
+			}
+			opts := m3uparser.FilterOptions{Mode: matchMode, MatchAll: *all, CaseSensitive: *caseSensitive}
+			collection := parser.Collection()
+			for _, key := range by.keys {
+				collection = collection.Filter(key, by.values[key], true, opts)
+			}
+			for _, key := range not.keys {
+				collection = collection.Filter(key, not.values[key], false, opts)
+			}
+			if *where != "" {
+				query, err := m3uparser.CompileQuery(*where)
+				if err != nil {
+					return collection, usageError(err.Error())
+				}
+				collection = collection.Match(query)
+			}
+			if *airing != "" && collection.Err() == nil {
+				guide, err := parser.LoadGuide(guides...)
+				if err != nil {
+					return collection, err
+				}
+				from := time.Now()
+				// a programme starting now is airing now
+				to := from.Add(*within + time.Nanosecond)
+				collection = collection.Airing(guide, *airing, from, to)
+			}
+			return collection, collection.Err()
+		}
+	})
+}
+
+// sortCommand sorts streams by keys.
+func sortCommand(env *env, args []string) error {
+	return pipe(env, args, "sort", "m3u", func(flags *flag.FlagSet) transform {
+		by := flags.String("by", "title", "comma separated `keys`, a leading - sorts descending, eg. category,-latency")
+		missingFirst := flags.Bool("missing-first", false, "put streams without a value first")
+		return func(parser *m3uparser.M3uParser) (m3uparser.Collection, error) {
+			keys, err := m3uparser.ParseSortKeys(*by)
+			if err != nil {
+				return m3uparser.Collection{}, usageError(err.Error())
+			}
+			for i := range keys {
+				keys[i].MissingFirst = *missingFirst
+			}
+			collection := parser.Collection().Sort(keys...)
+			return collection, collection.Err()
+		}
+	})
+}
+
+// check checks the streams and writes them with their status. With -fail it fails if a stream is BAD.
+// With -store the checks are recorded and the streams annotated with their history, see store.Store.
+func check(env *env, args []string) error {
+	flags := newFlagSet(env, "check", "[input...]")
+	in := addInputFlags(flags)
+	out := addOutputFlags(flags, "m3u")
+	only := flags.String("only", "", "write only GOOD or BAD streams")
+	fail := flags.Bool("fail", false, "fail if any stream is BAD")
+	storeFile := flags.String("store", "", "record the checks in the store `file` and add first_seen, last_seen, uptime and down_since keys")
+	window := flags.Duration("uptime-window", 0, "with -store, the uptime is of the checks in this window, 0 for all checks")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	status := strings.ToUpper(*only)
+	if status != "" && status != "GOOD" && status != "BAD" {
+		return usageErrorf("invalid -only %q, want good or bad", *only)
+	}
+	in.check = true
+	var bad int
+	err := pipeInputs(env, flags, in, out, func(parser *m3uparser.M3uParser) (m3uparser.Collection, error) {
+		collection := parser.Collection()
+		bad = collection.Stats().Bad
+		if *storeFile != "" {
+			var err error
+			if collection, err = record(collection, *storeFile, *window); err != nil {
+				return collection, err
+			}
+		}
+		if status != "" {
+			collection = collection.Filter("status", []string{status}, true, m3uparser.FilterOptions{Mode: m3uparser.MatchExact})
+		}
+		return collection, collection.Err()
+	})
+	if err == nil && *fail && bad > 0 {
+		err = fmt.Errorf("%d streams are BAD", bad)
+	}
+	return err
+}
+
+// record records the checks of collection in the store file and returns it annotated with the history.
+func record(collection m3uparser.Collection, storeFile string, window time.Duration) (m3uparser.Collection, error) {
+	st, err := store.Open(storeFile, store.Config{})
+	if err != nil {
+		return collection, err
+	}
+	now := time.Now()
+	if err := st.Record(collection, now); err != nil {
+		return collection, err
+	}
+	if err := st.Save(); err != nil {
+		return collection, err
+	}
+	var since time.Time
+	if window > 0 {
+		since = now.Add(-window)
+	}
+	return st.Annotate(collection, since), nil
+}
+
+var sampleWeights = map[string]m3uparser.WeightFunc{
+	"":        nil,
+	"good":    m3uparser.WeightGood,
+	"latency": m3uparser.WeightLatency,
+}
+
+// sample selects random streams.
+func sample(env *env, args []string) error {
+	return pipe(env, args, "sample", "m3u", func(flags *flag.FlagSet) transform {
+		n := flags.Int("n", 1, "number of streams to select")
+		perGroup := flags.String("per-group", "", "select n streams of every group of `key`, eg. category")
+		shuffle := flags.Bool("shuffle", false, "write all streams in random order instead")
+		seed := flags.Int64("seed", 0, "seed for a reproducible selection")
+		weight := flags.String("weight", "", "prefer good or low latency streams")
+		return func(parser *m3uparser.M3uParser) (m3uparser.Collection, error) {
+			weightFunc, ok := sampleWeights[*weight]
+			if !ok {
+				return m3uparser.Collection{}, usageErrorf("unknown weight %q, want good or latency", *weight)
+			}
+			if *n < 1 && !*shuffle {
+				return m3uparser.Collection{}, usageErrorf("-n must be positive, got %d", *n)
+			}
+			opts := m3uparser.SampleOptions{Seed: *seed, Weight: weightFunc}
+			collection := parser.Collection()
+			switch {
+			case *shuffle:
+				collection = collection.Shuffle(opts)
+			case *perGroup != "":
+				collection = collection.SamplePerGroup(*perGroup, *n, opts)
+			default:
+				collection = collection.Sample(*n, opts)
+			}
+			return collection, collection.Err()
+		}
+	})
+}
+
+// runPipeline runs a JSON or YAML pipeline spec, see m3uparser.PipelineSpec.
+func runPipeline(env *env, args []string) error {
+	flags := newFlagSet(env, "run", "<pipeline.json|pipeline.yaml>")
+	validate := flags.Bool("validate", false, "only validate the pipeline")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	pipeline, err := m3uparser.LoadPipeline(flags.Arg(0))
+	if err != nil || *validate {
+		return err
+	}
+	parser := m3uparser.M3uParser{}
+	if err := parser.RunPipeline(pipeline); err != nil {
+		return err
+	}
+	if len(pipeline.Spec().Outputs) == 0 {
+		out := outputFlags{output: "-", defaultFormat: "m3u"}
+		return out.write(parser.Collection(), env.stdout)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/epg"
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+// guide exports the XMLTV guide of the input streams or writes their current and next programme.
+func guide(env *env, args []string) error {
+	flags := newFlagSet(env, "guide", "[input...]")
+	in := addInputFlags(flags)
+	var guides stringsFlag
+	var renames keyValuesFlag
+	flags.Var(&guides, "guide", "XMLTV guide `source`, can be repeated (default the guides of the playlist)")
+	output := flags.String("o", "-", "guide `file`, gzip compressed if it ends with .gz, - for stdout")
+	window := flags.Duration("window", 0, "export the programmes airing from now until window, 0 for all")
+	flags.Var(&renames, "rename", "rename tvg-id `old=new` in the guide and the -playlist, can be repeated")
+	playlist := flags.String("playlist", "", "also write the playlist with renamed tvg-ids to `file`")
+	now := flags.Bool("now", false, "write the current and next programme of every stream instead")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	parser, err := in.load(flags.Args(), env.stdin)
+	if err != nil {
+		return err
+	}
+	loaded, err := parser.LoadGuide(guides...)
+	if err != nil {
+		return err
+	}
+	if *now {
+		tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+		for _, nowNext := range parser.NowNext(loaded, time.Now()) {
+			title, _ := nowNext.Channel["title"].(string)
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", title, programmeSummary(nowNext.Now), programmeSummary(nowNext.Next))
+		}
+		return tw.Flush()
+	}
+
+	opts := m3uparser.GuideExportOptions{Renames: make(map[string]string)}
+	for _, old := range renames.keys {
+		values := renames.values[old]
+		opts.Renames[old] = values[len(values)-1]
+	}
+	if *window > 0 {
+		opts.From = time.Now()
+		opts.To = opts.From.Add(*window)
+	}
+	parser.RenameTvgIDs(opts.Renames)
+	if *playlist != "" {
+		out := outputFlags{output: *playlist, defaultFormat: "m3u"}
+		if err := out.write(parser.Collection(), env.stdout); err != nil {
+			return err
+		}
+	}
+	if *output != "-" {
+		return parser.GuideToFile(loaded, *output, opts)
+	}
+	return parser.Collection().WriteGuide(env.stdout, loaded, opts)
+}
+
+// programmeSummary returns the start time and title of programme, "-" if there is none.
+func programmeSummary(programme *epg.Programme) string {
+	if programme == nil {
+		return "-"
+	}
+	return programme.Start.Local().Format("15:04") + " " + programme.Title
+}
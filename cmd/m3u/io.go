@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+// inputFlags - Flags of commands reading playlists.
+type inputFlags struct {
+	check         bool
+	timeout       int
+	userAgent     string
+	enforceSchema bool
+}
+
+func addInputFlags(flags *flag.FlagSet) *inputFlags {
+	in := &inputFlags{}
+	flags.BoolVar(&in.check, "check", false, "check if the streams are accessible and set their status")
+	flags.IntVar(&in.timeout, "timeout", 5, "timeout of requests and stream checks in seconds")
+	flags.StringVar(&in.userAgent, "user-agent", "", "user agent of requests")
+	flags.BoolVar(&in.enforceSchema, "enforce-schema", false, "keep keys with empty values")
+	return in
+}
+
+// isCSV reports whether input is a CSV or TSV file by its extension.
+func isCSV(input string) (bool, rune) {
+	switch strings.ToLower(filepath.Ext(input)) {
+	case ".csv":
+		return true, ','
+	case ".tsv":
+		return true, '\t'
+	}
+	return false, 0
+}
+
+// read returns the content of a file or URL.
+func (in *inputFlags) read(input string) ([]byte, error) {
+	if !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://") {
+		return ioutil.ReadFile(input)
+	}
+	// the client timeout covers reading the body, unlike the request context of m3uparser.Get
+	client := &http.Client{Timeout: time.Duration(in.timeout) * time.Second}
+	request, err := http.NewRequest(http.MethodGet, input, nil)
+	if err != nil {
+		return nil, err
+	}
+	if in.userAgent != "" {
+		request.Header.Set("User-Agent", in.userAgent)
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status code %d", input, resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// readCSV reads a CSV/TSV file or URL as JSON content, so it can be parsed with the other sources.
+func (in *inputFlags) readCSV(input string, comma rune) (string, error) {
+	content, err := in.read(input)
+	if err != nil {
+		return "", err
+	}
+	channels, err := m3uparser.ReadCSV(bytes.NewReader(content), m3uparser.CSVOptions{Comma: comma})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", input, err)
+	}
+	var buf bytes.Buffer
+	err = m3uparser.NewCollection(channels).WriteJSON(&buf)
+	return buf.String(), err
+}
+
+// load parses inputs, which are file paths, URLs or "-" for stdin, into a parser. Files ending
+// with .csv or .tsv are read as CSV/TSV, other inputs are detected by content. No input reads stdin.
+// URLs are requested with the -user-agent and must be read within the -timeout.
+// Every channel has the input it was read from under the "source" key. Stdin can only be given
+// once and the errors of all failed inputs are reported, one per line.
+func (in *inputFlags) load(inputs []string, stdin io.Reader) (*m3uparser.M3uParser, error) {
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	sources := make([]string, len(inputs))
+	readStdin := false
+	for i, input := range inputs {
+		if input == "-" {
+			if readStdin {
+				return nil, usageError("stdin (-) can only be read once")
+			}
+			readStdin = true
+			content, err := ioutil.ReadAll(stdin)
+			if err != nil {
+				return nil, err
+			}
+			// a raw source needs a line break to be told apart from a path
+			sources[i] = string(content) + "\n"
+			continue
+		}
+		if csv, comma := isCSV(input); csv {
+			content, err := in.readCSV(input, comma)
+			if err != nil {
+				return nil, err
+			}
+			sources[i] = content
+			continue
+		}
+		sources[i] = input
+	}
+	parser := &m3uparser.M3uParser{Timeout: in.timeout, UserAgent: in.userAgent}
+	if errs := parser.ParseSources(sources, in.check, in.enforceSchema); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		return nil, errors.New(strings.Join(messages, "\n"))
+	}
+	// name raw sources after their input
+	for _, channel := range parser.GetStreamsSlice() {
+		var i int
+		if source, _ := channel["source"].(string); strings.HasPrefix(source, "raw:") {
+			fmt.Sscanf(source, "raw:%d", &i)
+			channel["source"] = inputs[i]
+		}
+	}
+	return parser, nil
+}
+
+// outputFlags - Flags of commands writing playlists.
+type outputFlags struct {
+	output        string
+	format        string
+	defaultFormat string
+}
+
+func addOutputFlags(flags *flag.FlagSet, defaultFormat string) *outputFlags {
+	out := &outputFlags{defaultFormat: defaultFormat}
+	flags.StringVar(&out.output, "o", "-", "output `file`, - for stdout")
+	flags.StringVar(&out.format, "format", "", "output format: m3u, json, pls, xspf, asx, csv or tsv (default from -o extension, else "+defaultFormat+")")
+	return out
+}
+
+// write writes collection to the output in the output format.
+func (out *outputFlags) write(collection m3uparser.Collection, stdout io.Writer) error {
+	format := out.format
+	if format == "" && out.output != "-" {
+		format = strings.TrimPrefix(filepath.Ext(out.output), ".")
+	}
+	if format == "" {
+		format = out.defaultFormat
+	}
+	var buf bytes.Buffer
+	if err := collection.WriteFormat(&buf, format); err != nil {
+		return err
+	}
+	if out.output != "-" {
+		return ioutil.WriteFile(out.output, buf.Bytes(), 0644)
+	}
+	if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	_, err := stdout.Write(buf.Bytes())
+	return err
+}
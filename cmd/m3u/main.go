@@ -0,0 +1,190 @@
+// Command m3u works with M3U playlists from the command line.
+//
+// Usage:
+//
+//	m3u [-v] <command> [flags] [input...]
+//
+// Commands:
+//
+//	check    check the streams and write them with their status
+//	convert  convert playlists to another format
+//	dedupe   remove duplicate streams
+//	diff     show the streams added, removed and changed between two playlists
+//	filter   keep or remove streams by key, query or programme
+//	guide    export the XMLTV guide of the streams or show what's on now
+//	merge    merge playlists into one
+//	monitor  check sources on a schedule and report streams going down, coming back, added or removed
+//	parse    parse playlists into JSON
+//	run      run a pipeline spec
+//	sample   select random streams
+//	serve    serve filtered playlists of sources over HTTP
+//	sort     sort streams by keys
+//	stats    summarize playlists
+//
+// Inputs are file paths, URLs or "-" for stdin, which is read when no input is given.
+// Output goes to stdout unless -o names a file. Run "m3u <command> -h" for the flags of a command.
+//
+// The exit code is 0 on success, 1 on failure and 2 on invalid usage.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// env - The standard streams of a command.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// command - A subcommand of m3u.
+type command struct {
+	summary string
+	run     func(env *env, args []string) error
+}
+
+var commands = map[string]command{
+	"check":   {"check the streams and write them with their status", check},
+	"convert": {"convert playlists to another format", convert},
+	"dedupe":  {"remove duplicate streams", dedupe},
+	"diff":    {"show the streams added, removed and changed between two playlists", diff},
+	"filter":  {"keep or remove streams by key, query or programme", filter},
+	"guide":   {"export the XMLTV guide of the streams or show what's on now", guide},
+	"merge":   {"merge playlists into one", merge},
+	"monitor": {"check sources on a schedule and report streams going down, coming back, added or removed", monitorCommand},
+	"parse":   {"parse playlists into JSON", parse},
+	"run":     {"run a pipeline spec", runPipeline},
+	"sample":  {"select random streams", sample},
+	"serve":   {"serve filtered playlists of sources over HTTP", serve},
+	"sort":    {"sort streams by keys", sortCommand},
+	"stats":   {"summarize playlists", stats},
+}
+
+// errUsage is returned by commands given invalid flags or arguments after reporting them.
+var errUsage = errors.New("usage")
+
+// usageError - An invalid flag value or argument, reported with the usage exit code.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+// usage writes the commands to w.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: m3u [-v] <command> [flags] [input...]")
+	fmt.Fprintln(w, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nInputs are file paths, URLs or - for stdin, which is read when no input is given.")
+	fmt.Fprintln(w, "Run \"m3u <command> -h\" for the flags of a command, -v logs progress.")
+}
+
+// newFlagSet returns the flag set of a command reporting errors to stderr.
+func newFlagSet(env *env, name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: m3u %s [flags] %s\n\nFlags:\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses args with flags, returning errUsage if they are invalid. Flags may follow the
+// arguments, eg. "m3u sort in.m3u -by title", up to a "--" argument.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return err
+			}
+			return errUsage
+		}
+		rest := flags.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	// parsing only the terminator sets the arguments without changing the flags
+	return flags.Parse(append([]string{"--"}, positional...))
+}
+
+// stringsFlag - A flag that can be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// run runs the command of args and returns the exit code: 0 on success, 1 on failure and 2 on
+// invalid usage.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	log.SetOutput(stderr)
+	log.SetLevel(log.ErrorLevel)
+	if len(args) > 0 && (args[0] == "-v" || args[0] == "--verbose") {
+		log.SetLevel(log.InfoLevel)
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "m3u: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	err := cmd.run(&env{stdin: stdin, stdout: stdout, stderr: stderr}, args[1:])
+	if _, ok := err.(usageError); ok {
+		fmt.Fprintf(stderr, "m3u %s: %v\n", args[0], err)
+		return 2
+	}
+	switch err {
+	case nil, flag.ErrHelp:
+		return 0
+	case errUsage:
+		return 2
+	default:
+		fmt.Fprintf(stderr, "m3u %s: %v\n", args[0], err)
+		return 1
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const playlist = `#EXTM3U
+#EXTINF:-1 tvg-id="Alpha.np" tvg-country="NP" group-title="News",Alpha
+http://example.com/alpha.m3u8
+#EXTINF:-1 tvg-id="Beta.uk" tvg-country="UK" group-title="Sports",Beta
+http://example.com/beta.m3u8
+#EXTINF:-1 tvg-id="Gamma.np" tvg-country="NP" group-title="News",Gamma
+http://example.com/gamma.m3u8
+#EXTINF:-1 tvg-id="Alpha.np" tvg-country="NP" group-title="News",Alpha
+http://example.com/alpha.m3u8`
+
+// titles returns the titles of an M3U playlist in order.
+func titles(m3u string) []string {
+	var titles []string
+	for _, line := range strings.Split(m3u, "\n") {
+		if i := strings.LastIndex(line, ","); strings.HasPrefix(line, "#EXTINF") && i >= 0 {
+			titles = append(titles, line[i+1:])
+		}
+	}
+	return titles
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.m3u")
+	if err := ioutil.WriteFile(input, []byte(playlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args   []string
+		stdin  string
+		code   int
+		titles string
+	}{
+		{[]string{"convert", input}, "", 0, "Alpha Beta Gamma Alpha"},
+		{[]string{"convert"}, playlist, 0, "Alpha Beta Gamma Alpha"},
+		{[]string{"filter", "-by", "category=news", "-not", "title=gamma", input}, "", 0, "Alpha Alpha"},
+		{[]string{"filter", "-by", "country.code=uk", "-by", "country.code=np", "-mode", "exact", "-"}, playlist, 0, "Alpha Beta Gamma Alpha"},
+		{[]string{"filter", input, "-where", `title == "Beta"`}, "", 0, "Beta"},
+		{[]string{"sort", "-by", "-title", input}, "", 0, "Gamma Beta Alpha Alpha"},
+		{[]string{"dedupe", input}, "", 0, "Alpha Beta Gamma"},
+		{[]string{"merge", input, "-", "-dedupe", "tvg-id"}, playlist, 0, "Alpha Beta Gamma"},
+		{[]string{"sample", "-n", "2", "-seed", "1", input}, "", 0, ""},
+		{nil, "", 2, ""},
+		{[]string{"nope"}, "", 2, ""},
+		{[]string{"sort", "-nope", input}, "", 2, ""},
+		{[]string{"sort", "-by", "a..b", input}, "", 2, ""},
+		{[]string{"filter", "-where", "(", input}, "", 2, ""},
+		{[]string{"dedupe", "-by", "nope", input}, "", 2, ""},
+		{[]string{"sample", "-n", "-1", input}, "", 2, ""},
+		{[]string{"sample", "-n", "0", "-per-group", "category", input}, "", 2, ""},
+		{[]string{"diff", input}, "", 2, ""},
+		{[]string{"convert", filepath.Join(dir, "missing.m3u")}, "", 1, ""},
+		{[]string{"merge", "-", input, "-"}, playlist, 2, ""},
+		{[]string{"convert", "-format", "txt", input}, "", 1, ""},
+		{[]string{"sort", "-h"}, "", 0, ""},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+		if code != test.code {
+			t.Errorf("%v: exit code %d, want %d, stderr %s", test.args, code, test.code, stderr.String())
+			continue
+		}
+		if test.titles != "" && strings.Join(titles(stdout.String()), " ") != test.titles {
+			t.Errorf("%v: titles %v, want %s", test.args, titles(stdout.String()), test.titles)
+		}
+	}
+}
+
+func TestRunOutputs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.m3u")
+	ioutil.WriteFile(input, []byte(playlist), 0644)
+
+	// the output format follows the -o extension and csv inputs are read back
+	csv := filepath.Join(dir, "out.csv")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"dedupe", input, "-o", csv}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("dedupe: exit code %d, stderr %s", code, stderr.String())
+	}
+	if code := run([]string{"parse", csv}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("parse: exit code %d, stderr %s", code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "{") || strings.Count(stdout.String(), `"title"`) != 3 {
+		t.Errorf("parse of csv output:\n%s", stdout.String())
+	}
+
+	// the duplicate removed by dedupe is the only change
+	stdout.Reset()
+	if code := run([]string{"diff", input, csv, "-by", "tvg-id", "-exit-code"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("diff by tvg-id: exit code %d, want 1", code)
+	}
+	if want := "- Alpha http://example.com/alpha.m3u8\n0 added, 1 removed, 0 changed, 3 unchanged\n"; stdout.String() != want {
+		t.Errorf("diff by tvg-id: got %q, want %q", stdout.String(), want)
+	}
+	stdout.Reset()
+	changed := strings.Replace(playlist, `group-title="Sports"`, `group-title="Live Sports"`, 1)
+	if code := run([]string{"diff", input, "-"}, strings.NewReader(changed), &stdout, &stderr); code != 0 {
+		t.Errorf("diff: exit code %d, stderr %s", code, stderr.String())
+	}
+	if want := "~ Beta http://example.com/beta.m3u8\n    category: Sports -> Live Sports\n"; !strings.HasPrefix(stdout.String(), want) {
+		t.Errorf("diff: got %q, want prefix %q", stdout.String(), want)
+	}
+	if code := run([]string{"diff", "-format", "xml", input, input}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("diff with an unsupported format: exit code %d, want 2", code)
+	}
+	if code := run([]string{"diff", input, input}, nil, failingWriter{}, &stderr); code != 1 {
+		t.Errorf("diff to a failing output: exit code %d, want 1", code)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"stats", "-group-by", "country.code", input}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("stats: exit code %d", code)
+	}
+	if got := strings.Fields(stdout.String()); strings.Join(got, " ") != "NP 3 UK 1" {
+		t.Errorf("stats: got %q", stdout.String())
+	}
+}
+
+// failingWriter fails every write, like a closed pipe.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestRunInputs(t *testing.T) {
+	// a csv playlist sent in chunks is read completely
+	csv := "title,url,category\nAlpha,http://example.com/alpha.m3u8,News\nBeta,http://example.com/beta.m3u8,Sports\n"
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "m3u-test" {
+			http.Error(w, "unexpected user agent", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/stalled.m3u":
+			<-done
+			return
+		case "/list.m3u":
+			io.WriteString(w, "#EXTM3U\n#EXTINF:-1,Gamma\nhttp://example.com/gamma.m3u8\n")
+			return
+		}
+		for _, chunk := range []string{csv[:20], csv[20:]} {
+			io.WriteString(w, chunk)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"merge", "-user-agent", "m3u-test", server.URL + "/list.csv", server.URL + "/list.m3u"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("merge: exit code %d, stderr %s", code, stderr.String())
+	}
+	if got := strings.Join(titles(stdout.String()), " "); got != "Alpha Beta Gamma" {
+		t.Errorf("merge of chunked csv and m3u: titles %s", got)
+	}
+
+	// a server that never responds times out
+	start := time.Now()
+	if code := run([]string{"convert", "-timeout", "1", "-user-agent", "m3u-test", server.URL + "/stalled.m3u"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("convert of a stalled url: exit code %d, want 1", code)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("convert of a stalled url returned after %v", elapsed)
+	}
+
+	// every failed input is reported
+	dir := t.TempDir()
+	stderr.Reset()
+	missing := []string{filepath.Join(dir, "a.m3u"), filepath.Join(dir, "b.m3u")}
+	if code := run(append([]string{"merge"}, missing...), nil, &stdout, &stderr); code != 1 {
+		t.Errorf("merge of missing inputs: exit code %d, want 1", code)
+	}
+	for _, input := range missing {
+		if !strings.Contains(stderr.String(), input) {
+			t.Errorf("merge of missing inputs: %s not reported in %q", input, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+	"github.com/pawanpaudel93/go-m3u-parser/monitor"
+	"github.com/pawanpaudel93/go-m3u-parser/store"
+)
+
+// monitorCommand checks the source arguments on a schedule until interrupted and writes the
+// changes as "<time> <event> <title> <url>" lines, see monitor.Monitor. Failed scheduled checks,
+// including failures to save the store or notify a sink, are logged on stderr without -v.
+func monitorCommand(env *env, args []string) error {
+	flags := newFlagSet(env, "monitor", "<source>...")
+	interval := flags.Duration("interval", 15*time.Minute, "interval between checks")
+	timeout := flags.Int("timeout", 5, "timeout of stream checks in seconds")
+	userAgent := flags.String("user-agent", "", "user agent of requests")
+	by := flags.String("by", "tvg-id,url", "comma separated identities matching streams between checks, tried in order: tvg-id, url or title")
+	events := flags.String("events", "", "append the events as JSON lines to `file`")
+	storeFile := flags.String("store", "", "record the checks in the store `file`, see m3u check -store")
+	var webhooks stringsFlag
+	flags.Var(&webhooks, "webhook", "post the events of every check to `url`, can be repeated")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	config := monitor.Config{Sources: flags.Args(), Interval: *interval, Timeout: *timeout, UserAgent: *userAgent}
+	for _, name := range strings.Split(*by, ",") {
+		identity, err := m3uparser.ParseDiffIdentity(strings.TrimSpace(name))
+		if err != nil {
+			return usageError(err.Error())
+		}
+		config.Identities = append(config.Identities, identity)
+	}
+	if *storeFile != "" {
+		st, err := store.Open(*storeFile, store.Config{})
+		if err != nil {
+			return err
+		}
+		config.Store = st
+	}
+	config.Sinks = append(config.Sinks, monitor.SinkFunc(func(events []monitor.Event) error {
+		for _, event := range events {
+			fmt.Fprintf(env.stdout, "%s %s %s %s\n", event.Time.Format(time.RFC3339), event.Type, event.Title, event.URL)
+		}
+		return nil
+	}))
+	if *events != "" {
+		config.Sinks = append(config.Sinks, &monitor.JSONLines{FileName: *events})
+	}
+	for _, webhook := range webhooks {
+		config.Sinks = append(config.Sinks, monitor.Webhook{URL: webhook})
+	}
+
+	m := monitor.New(config)
+	if _, err := m.Check(); err != nil {
+		return err
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	m.Run(ctx)
+	return nil
+}
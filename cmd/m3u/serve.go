@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/server"
+	"github.com/pawanpaudel93/go-m3u-parser/xtream"
+	log "github.com/sirupsen/logrus"
+)
+
+// interruptContext returns a context done when the process is interrupted or cancel is called.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupt)
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// serve serves the playlists of the source arguments until interrupted.
+func serve(env *env, args []string) error {
+	flags := newFlagSet(env, "serve", "<source>...")
+	addr := flags.String("addr", ":8080", "address to listen on")
+	refresh := flags.Duration("refresh", time.Hour, "interval to reload the sources at, 0 to disable")
+	checkLive := flags.Bool("check", false, "check the streams on every load")
+	timeout := flags.Int("timeout", 5, "timeout of stream checks in seconds")
+	userAgent := flags.String("user-agent", "", "user agent of requests")
+	var users stringsFlag
+	flags.Var(&users, "user", "`name:password` of an Xtream Codes login, can be repeated")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+	logins := make(map[string]string, len(users))
+	for _, user := range users {
+		name, password := user, ""
+		if i := strings.Index(user, ":"); i >= 0 {
+			name, password = user[:i], user[i+1:]
+		}
+		if name == "" || password == "" {
+			return usageErrorf("invalid -user %q, want name:password", user)
+		}
+		logins[name] = password
+	}
+
+	handler := server.New(server.Config{
+		Sources:   flags.Args(),
+		Refresh:   *refresh,
+		CheckLive: *checkLive,
+		Timeout:   *timeout,
+		UserAgent: *userAgent,
+	})
+	if err := handler.Refresh(); err != nil {
+		return err
+	}
+	ctx, cancel := interruptContext()
+	defer cancel()
+	go handler.Run(ctx)
+
+	var root http.Handler = handler
+	if len(logins) > 0 {
+		// playlists are served under /playlist.*, anything else by the Xtream Codes server
+		xtreamServer := xtream.NewServer(xtream.ServerConfig{Users: logins, Collection: handler.Collection})
+		root = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, "/playlist.") {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			xtreamServer.ServeHTTP(w, r)
+		})
+	}
+	httpServer := &http.Server{Addr: *addr, Handler: root}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+	}()
+	log.Infof("Serving playlists on %s", *addr)
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
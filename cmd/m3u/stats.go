@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+// stats writes the stats of the inputs as tables or JSON, or the groups of a key.
+func stats(env *env, args []string) error {
+	flags := newFlagSet(env, "stats", "[input...]")
+	in := addInputFlags(flags)
+	asJSON := flags.Bool("json", false, "write JSON instead of tables")
+	groupBy := flags.String("group-by", "", "count the streams of every value of `key` instead, eg. country.code")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	parser, err := in.load(flags.Args(), env.stdin)
+	if err != nil {
+		return err
+	}
+	if *groupBy == "" {
+		if *asJSON {
+			return parser.Stats().WriteJSON(env.stdout)
+		}
+		return parser.Stats().WriteTable(env.stdout)
+	}
+	groups, err := parser.GroupBy(*groupBy)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	tw := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	for _, group := range groups {
+		key := group.Key
+		if key == "" {
+			key = "(none)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\n", key, len(group.Channels))
+	}
+	return tw.Flush()
+}
+
+// diff writes the streams added, removed and changed between two playlists.
+func diff(env *env, args []string) error {
+	flags := newFlagSet(env, "diff", "<old> <new>")
+	in := addInputFlags(flags)
+	by := flags.String("by", "tvg-id,url", "comma separated identities matching streams, tried in order: tvg-id, url or title")
+	fields := flags.String("fields", "", "comma separated key paths compared between matched streams (default "+strings.Join(m3uparser.DefaultDiffFields, ",")+")")
+	format := flags.String("format", "text", "output format: text, json or m3u")
+	exitCode := flags.Bool("exit-code", false, "fail if the playlists differ")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	var opts m3uparser.DiffOptions
+	for _, name := range strings.Split(*by, ",") {
+		identity, err := m3uparser.ParseDiffIdentity(strings.TrimSpace(name))
+		if err != nil {
+			return usageError(err.Error())
+		}
+		opts.Identities = append(opts.Identities, identity)
+	}
+	if *fields != "" {
+		opts.Fields = strings.Split(*fields, ",")
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errUsage
+	}
+	var collections [2]m3uparser.Collection
+	for i, input := range flags.Args() {
+		parser, err := in.load([]string{input}, env.stdin)
+		if err != nil {
+			return err
+		}
+		collections[i] = parser.Collection()
+	}
+	changes, err := m3uparser.Diff(collections[0], collections[1], opts)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	// written to a buffer first so only an unsupported format is a usage error
+	var buf bytes.Buffer
+	if err := changes.WriteFormat(&buf, *format); err != nil {
+		return usageError(err.Error())
+	}
+	if _, err := env.stdout.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if *exitCode && changes.Len() > 0 {
+		return fmt.Errorf("%d streams differ", changes.Len())
+	}
+	return nil
+}
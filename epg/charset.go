@@ -0,0 +1,55 @@
+package epg
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// windows1252 maps the bytes 0x80-0x9f of windows-1252 to runes, the other bytes are the same
+// as in ISO-8859-1. Undefined bytes map to the C1 control of the same value like browsers do.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// singleByteReader - A reader transcoding a single byte charset to UTF-8.
+type singleByteReader struct {
+	r       io.Reader
+	cp1252  bool
+	in      []byte
+	pending []byte
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		n, err := s.r.Read(s.in)
+		for _, b := range s.in[:n] {
+			r := rune(b)
+			if s.cp1252 && b >= 0x80 && b <= 0x9f {
+				r = windows1252[b-0x80]
+			}
+			s.pending = append(s.pending, string(r)...)
+		}
+		if n == 0 && err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// charsetReader returns a reader of input in charset transcoded to UTF-8. UTF-8 and ASCII are
+// read as is, ISO-8859-1 and windows-1252 are transcoded and other charsets are an error.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "latin-1", "l1":
+		return &singleByteReader{r: input, in: make([]byte, 4096)}, nil
+	case "windows-1252", "cp1252":
+		return &singleByteReader{r: input, cp1252: true, in: make([]byte, 4096)}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
@@ -0,0 +1,386 @@
+// Package epg reads XMLTV electronic programme guides and matches them to playlist channels.
+//
+// It does not depend on the m3uparser package, channels are matched through Ref so any
+// playlist representation can be used.
+package epg
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Channel - A <channel> of an XMLTV guide.
+type Channel struct {
+	ID           string
+	DisplayNames []string
+	Icon         string
+	URL          string
+	// attrs and inner are the attributes and raw content of the element, kept to write the channel unchanged.
+	attrs []xml.Attr
+	inner string
+}
+
+// Programme - A <programme> of an XMLTV guide.
+type Programme struct {
+	// Channel is the id of the channel the programme airs on.
+	Channel     string
+	Start, Stop time.Time
+	Title       string
+	SubTitle    string
+	Desc        string
+	Categories  []string
+	Icon        string
+	// EpisodeNum is the first episode number, eg. "1.5.0/1" of the xmltv_ns system.
+	EpisodeNum string
+	// attrs and inner are the attributes and raw content of the element, kept to write the programme unchanged.
+	attrs []xml.Attr
+	inner string
+}
+
+// Airing reports whether the programme is on air at t.
+func (p Programme) Airing(t time.Time) bool {
+	return !t.Before(p.Start) && (p.Stop.IsZero() || t.Before(p.Stop))
+}
+
+// Overlaps reports whether the programme airs at any time between from and to.
+// A zero from or to leaves that side of the range open.
+func (p Programme) Overlaps(from, to time.Time) bool {
+	if !to.IsZero() && !p.Start.Before(to) {
+		return false
+	}
+	return from.IsZero() || p.Stop.IsZero() || p.Stop.After(from)
+}
+
+type xmlChannel struct {
+	ID           string   `xml:"id,attr"`
+	DisplayNames []string `xml:"display-name"`
+	Icon         struct {
+		Src string `xml:"src,attr"`
+	} `xml:"icon"`
+	URL   string `xml:"url"`
+	Inner string `xml:",innerxml"`
+}
+
+type xmlProgramme struct {
+	Start      string   `xml:"start,attr"`
+	Stop       string   `xml:"stop,attr"`
+	Channel    string   `xml:"channel,attr"`
+	Titles     []string `xml:"title"`
+	SubTitles  []string `xml:"sub-title"`
+	Descs      []string `xml:"desc"`
+	Categories []string `xml:"category"`
+	Icon       struct {
+		Src string `xml:"src,attr"`
+	} `xml:"icon"`
+	EpisodeNums []string `xml:"episode-num"`
+	Inner       string   `xml:",innerxml"`
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// timeLayouts are the XMLTV date formats from the most to the least precise. Times without
+// a timezone are UTC.
+var timeLayouts = []string{
+	"20060102150405 -0700", "20060102150405 MST", "20060102150405",
+	"200601021504 -0700", "200601021504", "2006010215", "20060102",
+}
+
+// ParseTime parses an XMLTV date like "20080715003000 -0600".
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid xmltv time %q", value)
+}
+
+// FormatTime formats t as XMLTV date with timezone offset.
+func FormatTime(t time.Time) string {
+	return t.Format("20060102150405 -0700")
+}
+
+// Handler - Callbacks of Decode. A nil callback skips the elements.
+// Returning an error stops decoding and is returned by Decode.
+type Handler struct {
+	// TV is called with the attributes of the root <tv> element.
+	TV        func(attrs []xml.Attr) error
+	Channel   func(Channel) error
+	Programme func(Programme) error
+}
+
+// NewReader returns a reader of the uncompressed content of r, which may be gzip compressed.
+func NewReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+// Decode streams the channels and programmes of an XMLTV guide, which may be gzip compressed,
+// to handler one element at a time so large guides are never held in memory.
+// Programmes with an invalid start time are an error.
+func Decode(r io.Reader, handler Handler) error {
+	reader, err := NewReader(r)
+	if err != nil {
+		return err
+	}
+	decoder := xml.NewDecoder(reader)
+	// guides in the wild declare encodings like ISO-8859-1, transcode them to UTF-8.
+	decoder.CharsetReader = charsetReader
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid xmltv: %v", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "tv":
+			if handler.TV != nil {
+				if err := handler.TV(start.Attr); err != nil {
+					return err
+				}
+			}
+		case "channel":
+			if handler.Channel == nil {
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("invalid xmltv: %v", err)
+				}
+				continue
+			}
+			var element xmlChannel
+			if err := decoder.DecodeElement(&element, &start); err != nil {
+				return fmt.Errorf("invalid xmltv channel: %v", err)
+			}
+			names := make([]string, 0, len(element.DisplayNames))
+			for _, name := range element.DisplayNames {
+				if name = strings.TrimSpace(name); name != "" {
+					names = append(names, name)
+				}
+			}
+			channel := Channel{
+				ID:           strings.TrimSpace(element.ID),
+				DisplayNames: names,
+				Icon:         element.Icon.Src,
+				URL:          strings.TrimSpace(element.URL),
+				attrs:        start.Attr,
+				inner:        element.Inner,
+			}
+			if err := handler.Channel(channel); err != nil {
+				return err
+			}
+		case "programme":
+			if handler.Programme == nil {
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("invalid xmltv: %v", err)
+				}
+				continue
+			}
+			var element xmlProgramme
+			if err := decoder.DecodeElement(&element, &start); err != nil {
+				return fmt.Errorf("invalid xmltv programme: %v", err)
+			}
+			programme := Programme{
+				Channel:    strings.TrimSpace(element.Channel),
+				Title:      first(element.Titles),
+				SubTitle:   first(element.SubTitles),
+				Desc:       first(element.Descs),
+				Icon:       element.Icon.Src,
+				EpisodeNum: first(element.EpisodeNums),
+				attrs:      start.Attr,
+				inner:      element.Inner,
+			}
+			for _, category := range element.Categories {
+				if category = strings.TrimSpace(category); category != "" {
+					programme.Categories = append(programme.Categories, category)
+				}
+			}
+			if programme.Start, err = ParseTime(element.Start); err != nil {
+				return fmt.Errorf("programme %q of channel %q: %v", programme.Title, programme.Channel, err)
+			}
+			if element.Stop != "" {
+				if programme.Stop, err = ParseTime(element.Stop); err != nil {
+					return fmt.Errorf("programme %q of channel %q: %v", programme.Title, programme.Channel, err)
+				}
+			}
+			if err := handler.Programme(programme); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Guide - The channels and programmes of an XMLTV guide.
+type Guide struct {
+	attrs      []xml.Attr
+	channels   []Channel
+	channelIDs map[string]int
+	programmes map[string][]Programme
+	index      *matchIndex
+}
+
+// NewGuide returns an empty guide.
+func NewGuide() *Guide {
+	return &Guide{channelIDs: make(map[string]int), programmes: make(map[string][]Programme)}
+}
+
+// Parse reads a whole XMLTV guide, which may be gzip compressed.
+func Parse(r io.Reader) (*Guide, error) {
+	guide := NewGuide()
+	err := Decode(r, Handler{
+		TV: func(attrs []xml.Attr) error {
+			guide.attrs = attrs
+			return nil
+		},
+		Channel: func(channel Channel) error {
+			guide.AddChannel(channel)
+			return nil
+		},
+		Programme: func(programme Programme) error {
+			guide.AddProgramme(programme)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return guide, nil
+}
+
+// loadClient downloads the guides of Load. Its timeout covers reading the body, guides can be large.
+var loadClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Load reads an XMLTV guide from a URL or file path, which may be gzip compressed. URLs must be
+// read within 2 minutes, use LoadWithClient for another timeout.
+func Load(source string) (*Guide, error) {
+	return LoadWithClient(source, loadClient)
+}
+
+// LoadWithClient is like Load but downloads URLs with client.
+func LoadWithClient(source string, client *http.Client) (*Guide, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		log.Infoln("Started loading guide URL...")
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		return Parse(resp.Body)
+	}
+	log.Infoln("Started loading guide file...")
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// AddChannel adds channel to the guide, replacing a channel with the same id.
+func (g *Guide) AddChannel(channel Channel) {
+	g.index = nil
+	if i, ok := g.channelIDs[channel.ID]; ok {
+		g.channels[i] = channel
+		return
+	}
+	g.channelIDs[channel.ID] = len(g.channels)
+	g.channels = append(g.channels, channel)
+}
+
+// AddProgramme adds programme to the guide keeping the programmes of its channel ordered by start.
+func (g *Guide) AddProgramme(programme Programme) {
+	g.index = nil
+	programmes := g.programmes[programme.Channel]
+	i := sort.Search(len(programmes), func(i int) bool {
+		return programmes[i].Start.After(programme.Start)
+	})
+	programmes = append(programmes, Programme{})
+	copy(programmes[i+1:], programmes[i:])
+	programmes[i] = programme
+	g.programmes[programme.Channel] = programmes
+}
+
+// Merge adds the channels and programmes of other to the guide. Channels of other replace
+// channels with the same id.
+func (g *Guide) Merge(other *Guide) {
+	for _, channel := range other.channels {
+		g.AddChannel(channel)
+	}
+	for _, programmes := range other.programmes {
+		for _, programme := range programmes {
+			g.AddProgramme(programme)
+		}
+	}
+	if g.attrs == nil {
+		g.attrs = other.attrs
+	}
+}
+
+// Attrs returns the attributes of the root <tv> element, eg. generator-info-name.
+func (g *Guide) Attrs() []xml.Attr {
+	return append([]xml.Attr(nil), g.attrs...)
+}
+
+// SetAttrs sets the attributes of the root <tv> element.
+func (g *Guide) SetAttrs(attrs []xml.Attr) {
+	g.attrs = append([]xml.Attr(nil), attrs...)
+}
+
+// Channels returns the channels in guide order.
+func (g *Guide) Channels() []Channel {
+	channels := make([]Channel, len(g.channels))
+	copy(channels, g.channels)
+	return channels
+}
+
+// Channel returns the channel with id.
+func (g *Guide) Channel(id string) (Channel, bool) {
+	i, ok := g.channelIDs[id]
+	if !ok {
+		return Channel{}, false
+	}
+	return g.channels[i], true
+}
+
+// Programmes returns the programmes of the channel with id ordered by start.
+func (g *Guide) Programmes(id string) []Programme {
+	programmes := make([]Programme, len(g.programmes[id]))
+	copy(programmes, g.programmes[id])
+	return programmes
+}
+
+// Len returns the number of programmes in the guide.
+func (g *Guide) Len() int {
+	n := 0
+	for _, programmes := range g.programmes {
+		n += len(programmes)
+	}
+	return n
+}
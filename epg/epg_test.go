@@ -0,0 +1,167 @@
+package epg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGuide = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<!DOCTYPE tv SYSTEM "xmltv.dtd">
+<tv generator-info-name="test">
+  <channel id="KantipurTV.np">
+    <display-name>Kantipur TV HD</display-name>
+    <display-name>KTV</display-name>
+    <icon src="http://example.com/ktv.png"/>
+  </channel>
+  <channel id="ImageChannel.np">
+    <display-name>Image Channel</display-name>
+  </channel>
+  <channel id="Empty.np">
+    <display-name>Empty</display-name>
+  </channel>
+  <programme start="20240101110000 +0000" stop="20240101120000 +0000" channel="KantipurTV.np">
+    <title lang="ne">Late News</title>
+    <category>News</category>
+  </programme>
+  <programme start="20240101100000 +0000" stop="20240101110000 +0000" channel="KantipurTV.np">
+    <title lang="ne">Morning News</title>
+    <sub-title>Headlines</sub-title>
+    <desc>Daily news.</desc>
+    <episode-num system="xmltv_ns">1.5.0/1</episode-num>
+  </programme>
+  <programme start="20240101160000 +0545" channel="ImageChannel.np">
+    <title>Music</title>
+  </programme>
+  <programme start="20240101100000" stop="20240101110000" channel="Undeclared.np">
+    <title>Undeclared</title>
+  </programme>
+</tv>`
+
+func TestParse(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(testGuide))
+	writer.Close()
+
+	for name, content := range map[string][]byte{"plain": []byte(testGuide), "gzip": compressed.Bytes()} {
+		guide, err := Parse(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(guide.Channels()) != 3 || guide.Len() != 4 {
+			t.Fatalf("%s: expected 3 channels and 4 programmes, got %d and %d", name, len(guide.Channels()), guide.Len())
+		}
+		channel, ok := guide.Channel("KantipurTV.np")
+		if !ok || channel.Icon != "http://example.com/ktv.png" || len(channel.DisplayNames) != 2 {
+			t.Errorf("%s: unexpected channel %+v", name, channel)
+		}
+		programmes := guide.Programmes("KantipurTV.np")
+		if len(programmes) != 2 || programmes[0].Title != "Morning News" || programmes[1].Title != "Late News" {
+			t.Fatalf("%s: expected programmes ordered by start, got %+v", name, programmes)
+		}
+		morning := programmes[0]
+		if morning.SubTitle != "Headlines" || morning.Desc != "Daily news." || morning.EpisodeNum != "1.5.0/1" {
+			t.Errorf("%s: unexpected programme %+v", name, morning)
+		}
+		if !morning.Start.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: unexpected start %v", name, morning.Start)
+		}
+		music := guide.Programmes("ImageChannel.np")[0]
+		if !music.Start.Equal(time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)) || !music.Stop.IsZero() {
+			t.Errorf("%s: unexpected times %v %v", name, music.Start, music.Stop)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`<tv><programme start="yesterday" channel="a"><title>A</title></programme></tv>`)); err == nil ||
+		!strings.Contains(err.Error(), `invalid xmltv time "yesterday"`) {
+		t.Errorf("Expected invalid time error, got %v", err)
+	}
+	if _, err := Parse(strings.NewReader(`<tv><channel id="a">`)); err == nil {
+		t.Error("Expected error for truncated guide")
+	}
+}
+
+func TestParseCharsets(t *testing.T) {
+	guide := func(charset, name string) string {
+		return `<?xml version="1.0" encoding="` + charset + `"?><tv><channel id="a"><display-name>` + name + `</display-name></channel></tv>`
+	}
+	tests := []struct {
+		charset, name, expected string
+	}{
+		{"ISO-8859-1", "T\xe9l\xe9 \x80", "Télé \u0080"},
+		{"latin1", "Espa\xf1a", "España"},
+		{"windows-1252", "\x93News\x94 \x80", "“News” €"},
+		{"UTF-8", "Télé", "Télé"},
+	}
+	for _, test := range tests {
+		parsed, err := Parse(strings.NewReader(guide(test.charset, test.name)))
+		if err != nil {
+			t.Errorf("%s: %v", test.charset, err)
+			continue
+		}
+		if name := parsed.Channels()[0].DisplayNames[0]; name != test.expected {
+			t.Errorf("%s: display name %q, want %q", test.charset, name, test.expected)
+		}
+	}
+	if _, err := Parse(strings.NewReader(guide("KOI8-R", "\xf4"))); err == nil || !strings.Contains(err.Error(), `unsupported charset "KOI8-R"`) {
+		t.Errorf("Expected an unsupported charset error, got %v", err)
+	}
+}
+
+func TestDecodeStreaming(t *testing.T) {
+	var titles []string
+	err := Decode(strings.NewReader(testGuide), Handler{
+		Programme: func(programme Programme) error {
+			titles = append(titles, programme.Title)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(titles, ",") != "Late News,Morning News,Music,Undeclared" {
+		t.Errorf("Expected programmes in document order, got %v", titles)
+	}
+}
+
+func TestProgrammeAiring(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	programme := Programme{Start: start, Stop: start.Add(time.Hour)}
+	if !programme.Airing(start) || programme.Airing(start.Add(time.Hour)) || programme.Airing(start.Add(-time.Second)) {
+		t.Error("Unexpected airing result")
+	}
+	if !programme.Overlaps(start.Add(30*time.Minute), start.Add(2*time.Hour)) || programme.Overlaps(start.Add(time.Hour), time.Time{}) {
+		t.Error("Unexpected overlaps result")
+	}
+}
+
+func TestLoadTimeout(t *testing.T) {
+	// the server sends the headers and then stalls the guide
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<tv>")
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	timeout := loadClient.Timeout
+	loadClient.Timeout = 100 * time.Millisecond
+	defer func() { loadClient.Timeout = timeout }()
+	start := time.Now()
+	if _, err := Load(server.URL); err == nil {
+		t.Error("Expected an error for a stalled guide")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stalled guide returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,153 @@
+package epg
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchMethod - How a playlist channel was matched to a guide channel.
+type MatchMethod int
+
+const (
+	// NoMatch - The channel has no guide channel.
+	NoMatch MatchMethod = iota
+	// MatchedByID - tvg-id equals the guide channel id, ignoring case.
+	MatchedByID
+	// MatchedByName - tvg-name equals a display-name, ignoring case.
+	MatchedByName
+	// MatchedByDisplayName - The normalized tvg-name or title equals a normalized display-name.
+	MatchedByDisplayName
+)
+
+func (m MatchMethod) String() string {
+	switch m {
+	case MatchedByID:
+		return "id"
+	case MatchedByName:
+		return "name"
+	case MatchedByDisplayName:
+		return "display-name"
+	}
+	return "none"
+}
+
+// Ref - The identity of a playlist channel used for matching.
+type Ref struct {
+	// ID is the tvg-id.
+	ID string
+	// Name is the tvg-name.
+	Name string
+	// Title is the channel title.
+	Title string
+}
+
+var (
+	nameQualifierRegex = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+	nameNonWordRegex   = regexp.MustCompile(`[^\pL\pN]+`)
+	nameQualityRegex   = regexp.MustCompile(`\b(hd|fhd|uhd|sd|4k|hevc)\b`)
+)
+
+// NormalizeName lowercases name and removes qualifiers like "(1080p)", "[Geo-blocked]" or "HD"
+// and punctuation, so "Kantipur TV HD (720p)" and "Kantipur-TV" are equal.
+func NormalizeName(name string) string {
+	name = nameQualifierRegex.ReplaceAllString(strings.ToLower(name), " ")
+	name = nameNonWordRegex.ReplaceAllString(name, " ")
+	name = nameQualityRegex.ReplaceAllString(name, " ")
+	return strings.Join(strings.Fields(name), "")
+}
+
+// matchIndex maps the keys of every match method to guide channel ids. The first channel
+// of the guide wins when several share a key.
+type matchIndex struct {
+	ids        map[string]string
+	names      map[string]string
+	normalized map[string]string
+}
+
+func addKey(index map[string]string, key, id string) {
+	if _, ok := index[key]; key != "" && !ok {
+		index[key] = id
+	}
+}
+
+func (g *Guide) matchIndex() *matchIndex {
+	if g.index != nil {
+		return g.index
+	}
+	index := &matchIndex{ids: make(map[string]string), names: make(map[string]string), normalized: make(map[string]string)}
+	for _, channel := range g.channels {
+		addKey(index.ids, strings.ToLower(channel.ID), channel.ID)
+		for _, name := range channel.DisplayNames {
+			addKey(index.names, strings.ToLower(name), channel.ID)
+			addKey(index.normalized, NormalizeName(name), channel.ID)
+		}
+	}
+	// programmes may reference channels the guide doesn't declare
+	for id := range g.programmes {
+		addKey(index.ids, strings.ToLower(id), id)
+	}
+	g.index = index
+	return index
+}
+
+// Match returns the id of the guide channel of ref and how it was matched.
+// It tries the tvg-id, then the tvg-name against display-names and then the normalized tvg-name
+// and title against normalized display-names. It returns NoMatch if nothing matches.
+func (g *Guide) Match(ref Ref) (string, MatchMethod) {
+	index := g.matchIndex()
+	if id, ok := index.ids[strings.ToLower(strings.TrimSpace(ref.ID))]; ok && ref.ID != "" {
+		return id, MatchedByID
+	}
+	if id, ok := index.names[strings.ToLower(strings.TrimSpace(ref.Name))]; ok && ref.Name != "" {
+		return id, MatchedByName
+	}
+	for _, name := range []string{ref.Name, ref.Title} {
+		if id, ok := index.normalized[NormalizeName(name)]; ok && NormalizeName(name) != "" {
+			return id, MatchedByDisplayName
+		}
+	}
+	return "", NoMatch
+}
+
+// ProgrammesFor returns the programmes of the guide channel of ref ordered by start, nil if it has no match.
+func (g *Guide) ProgrammesFor(ref Ref) []Programme {
+	id, method := g.Match(ref)
+	if method == NoMatch {
+		return nil
+	}
+	return g.Programmes(id)
+}
+
+// Match - The guide channel of a playlist channel.
+type Match struct {
+	Ref Ref
+	// ChannelID is the id of the guide channel, empty for NoMatch.
+	ChannelID string
+	Method    MatchMethod
+	// Programmes is the number of programmes of the guide channel.
+	Programmes int
+}
+
+// Report - The result of matching playlist channels to a guide.
+type Report struct {
+	// Matches has a match for every channel in the given order, including NoMatch.
+	Matches []Match
+	// Missing are the channels without guide data: no guide channel or no programmes.
+	Missing []Ref
+}
+
+// MatchAll matches every ref and reports the channels without guide data.
+func (g *Guide) MatchAll(refs []Ref) Report {
+	report := Report{Matches: make([]Match, len(refs))}
+	for i, ref := range refs {
+		id, method := g.Match(ref)
+		report.Matches[i] = Match{Ref: ref, ChannelID: id, Method: method}
+		if method != NoMatch {
+			report.Matches[i].Programmes = len(g.programmes[id])
+		}
+		if report.Matches[i].Programmes == 0 {
+			report.Missing = append(report.Missing, ref)
+		}
+	}
+	return report
+}
@@ -0,0 +1,54 @@
+package epg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	for _, name := range []string{"Kantipur TV HD (720p)", "Kantipur-TV", "kantipur tv [Geo-blocked]"} {
+		if NormalizeName(name) != "kantipurtv" {
+			t.Errorf("Expected %q to normalize to kantipurtv, got %q", name, NormalizeName(name))
+		}
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	guide, err := Parse(strings.NewReader(testGuide))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := []Ref{
+		{ID: "kantipurtv.np"},
+		{ID: "Unknown.np", Name: "KTV"},
+		{Name: "Image-Channel", Title: "Other"},
+		{Title: "Kantipur TV (1080p)"},
+		{ID: "Undeclared.np"},
+		{ID: "Empty.np"},
+		{Title: "Missing"},
+	}
+	report := guide.MatchAll(refs)
+	expected := []struct {
+		id     string
+		method MatchMethod
+	}{
+		{"KantipurTV.np", MatchedByID},
+		{"KantipurTV.np", MatchedByName},
+		{"ImageChannel.np", MatchedByDisplayName},
+		{"KantipurTV.np", MatchedByDisplayName},
+		{"Undeclared.np", MatchedByID},
+		{"Empty.np", MatchedByID},
+		{"", NoMatch},
+	}
+	for i, match := range report.Matches {
+		if match.ChannelID != expected[i].id || match.Method != expected[i].method {
+			t.Errorf("%+v: expected %s by %s, got %s by %s", refs[i], expected[i].id, expected[i].method, match.ChannelID, match.Method)
+		}
+	}
+	if len(report.Missing) != 2 || report.Missing[0].ID != "Empty.np" || report.Missing[1].Title != "Missing" {
+		t.Errorf("Expected Empty.np and Missing without guide data, got %+v", report.Missing)
+	}
+	if programmes := guide.ProgrammesFor(Ref{Name: "ktv"}); len(programmes) != 2 || programmes[0].Title != "Morning News" {
+		t.Errorf("Unexpected programmes %+v", programmes)
+	}
+}
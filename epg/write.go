@@ -0,0 +1,150 @@
+package epg
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeAttrs writes the attributes of an element. Attributes named in replace are written with
+// their new value in place of the original ones and appended if the element has none.
+func writeAttrs(w *bufio.Writer, attrs []xml.Attr, replace [][2]string) {
+	written := make(map[string]bool)
+	write := func(name, value string) {
+		w.WriteString(" " + name + `="`)
+		xml.EscapeText(w, []byte(value))
+		w.WriteString(`"`)
+	}
+	for _, attr := range attrs {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		value := attr.Value
+		for _, r := range replace {
+			if r[0] == name {
+				value = r[1]
+				written[name] = true
+			}
+		}
+		if value != "" {
+			write(name, value)
+		}
+	}
+	for _, r := range replace {
+		if !written[r[0]] && r[1] != "" {
+			write(r[0], r[1])
+		}
+	}
+}
+
+// writeElement writes a text element like <title>News</title>.
+func writeElement(w *bufio.Writer, name, text string) {
+	if text == "" {
+		return
+	}
+	w.WriteString("\n    <" + name + ">")
+	xml.EscapeText(w, []byte(text))
+	w.WriteString("</" + name + ">")
+}
+
+// writeIcon writes an <icon src=""/> element.
+func writeIcon(w *bufio.Writer, src string) {
+	if src == "" {
+		return
+	}
+	w.WriteString("\n    <icon")
+	writeAttrs(w, nil, [][2]string{{"src", src}})
+	w.WriteString("/>")
+}
+
+func (c Channel) write(w *bufio.Writer) {
+	w.WriteString("  <channel")
+	writeAttrs(w, c.attrs, [][2]string{{"id", c.ID}})
+	w.WriteString(">")
+	if c.inner != "" {
+		w.WriteString(c.inner)
+	} else {
+		for _, name := range c.DisplayNames {
+			writeElement(w, "display-name", name)
+		}
+		writeIcon(w, c.Icon)
+		writeElement(w, "url", c.URL)
+		w.WriteString("\n  ")
+	}
+	w.WriteString("</channel>\n")
+}
+
+func (p Programme) write(w *bufio.Writer) {
+	stop := ""
+	if !p.Stop.IsZero() {
+		stop = FormatTime(p.Stop)
+	}
+	w.WriteString("  <programme")
+	writeAttrs(w, p.attrs, [][2]string{{"start", FormatTime(p.Start)}, {"stop", stop}, {"channel", p.Channel}})
+	w.WriteString(">")
+	if p.inner != "" {
+		w.WriteString(p.inner)
+	} else {
+		writeElement(w, "title", p.Title)
+		writeElement(w, "sub-title", p.SubTitle)
+		writeElement(w, "desc", p.Desc)
+		for _, category := range p.Categories {
+			writeElement(w, "category", category)
+		}
+		writeIcon(w, p.Icon)
+		if p.EpisodeNum != "" {
+			w.WriteString("\n    <episode-num")
+			writeAttrs(w, nil, [][2]string{{"system", "xmltv_ns"}})
+			w.WriteString(">")
+			xml.EscapeText(w, []byte(p.EpisodeNum))
+			w.WriteString("</episode-num>")
+		}
+		w.WriteString("\n  ")
+	}
+	w.WriteString("</programme>\n")
+}
+
+// Write writes the guide as XMLTV. Channels are written in guide order followed by the programmes
+// of every channel ordered by start. Elements read from a guide are written unchanged except for
+// their channel id and times, so ids can be renamed by changing Channel.ID and Programme.Channel.
+func (g *Guide) Write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString(xml.Header)
+	writer.WriteString(`<!DOCTYPE tv SYSTEM "xmltv.dtd">` + "\n")
+	writer.WriteString("<tv")
+	writeAttrs(writer, g.attrs, nil)
+	writer.WriteString(">\n")
+	for _, channel := range g.channels {
+		channel.write(writer)
+	}
+	ids := make([]string, 0, len(g.programmes))
+	for _, channel := range g.channels {
+		ids = append(ids, channel.ID)
+	}
+	// programmes of undeclared channels follow in id order
+	var undeclared []string
+	for id := range g.programmes {
+		if _, ok := g.channelIDs[id]; !ok {
+			undeclared = append(undeclared, id)
+		}
+	}
+	sort.Strings(undeclared)
+	ids = append(ids, undeclared...)
+	for _, id := range ids {
+		for _, programme := range g.programmes[id] {
+			programme.write(writer)
+		}
+	}
+	writer.WriteString("</tv>\n")
+	return writer.Flush()
+}
+
+// String returns the guide as XMLTV.
+func (g *Guide) String() string {
+	var sb strings.Builder
+	g.Write(&sb)
+	return sb.String()
+}
@@ -0,0 +1,60 @@
+package epg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	guide, err := Parse(strings.NewReader(testGuide))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := guide.String()
+	if !strings.Contains(content, `<tv generator-info-name="test">`) || !strings.Contains(content, `<title lang="ne">Morning News</title>`) {
+		t.Errorf("Expected attributes and content to be kept, got %s", content)
+	}
+	written, err := Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written.Channels(), guide.Channels()) {
+		t.Errorf("Expected channels %+v, got %+v", guide.Channels(), written.Channels())
+	}
+	for _, id := range []string{"KantipurTV.np", "ImageChannel.np", "Undeclared.np"} {
+		if len(written.Programmes(id)) != len(guide.Programmes(id)) {
+			t.Errorf("%s: expected %d programmes, got %d", id, len(guide.Programmes(id)), len(written.Programmes(id)))
+		}
+	}
+}
+
+func TestWriteRenamed(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	guide := NewGuide()
+	guide.AddChannel(Channel{ID: "a&b", DisplayNames: []string{"A & B"}, Icon: "http://example.com/a.png"})
+	guide.AddProgramme(Programme{Channel: "a&b", Start: start, Stop: start.Add(time.Hour), Title: "News <live>", Categories: []string{"News"}})
+
+	written, err := Parse(strings.NewReader(guide.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel, ok := written.Channel("a&b")
+	if !ok || channel.DisplayNames[0] != "A & B" || channel.Icon != "http://example.com/a.png" {
+		t.Errorf("Unexpected channel %+v", channel)
+	}
+	programmes := written.Programmes("a&b")
+	if len(programmes) != 1 || programmes[0].Title != "News <live>" || !programmes[0].Stop.Equal(start.Add(time.Hour)) {
+		t.Errorf("Unexpected programmes %+v", programmes)
+	}
+
+	programmes[0].Channel = "renamed"
+	channel.ID = "renamed"
+	renamed := NewGuide()
+	renamed.AddChannel(channel)
+	renamed.AddProgramme(programmes[0])
+	if content := renamed.String(); strings.Contains(content, "a&amp;b") || !strings.Contains(content, `channel="renamed"`) {
+		t.Errorf("Expected ids to be renamed, got %s", content)
+	}
+}
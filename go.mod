@@ -6,4 +6,5 @@ require (
 	github.com/cheggaaa/pb/v3 v3.1.7
 	github.com/pirsquare/country-mapper v0.0.0-20180107162822-0fffc2d62977
 	github.com/sirupsen/logrus v1.9.3
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,214 @@
+package m3uparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Catch-up modes of the catchup attribute.
+const (
+	// CatchupDefault - catchup-source is the whole archive URL.
+	CatchupDefault = "default"
+	// CatchupAppend - catchup-source is appended to the stream URL.
+	CatchupAppend = "append"
+	// CatchupShift - utc and lutc query parameters are appended to the stream URL.
+	CatchupShift = "shift"
+	// CatchupFlussonic - The archive URL is derived from a Flussonic stream URL.
+	CatchupFlussonic = "flussonic"
+	// CatchupXC - The archive URL is derived from an Xtream Codes stream URL.
+	CatchupXC = "xc"
+)
+
+// catchupAttributes are the #EXTINF attributes describing catch-up, inherited from the #EXTM3U header.
+// Attributes of a group are aliases, a channel having one of them doesn't inherit the others.
+var catchupAttributes = [][]string{{"catchup", "catchup-type"}, {"catchup-source"}, {"catchup-days", "timeshift"}}
+
+// inheritCatchup sets the catch-up attributes of the header on channels that don't have their own.
+func inheritCatchup(channels []Channel, header map[string]string) {
+	for _, channel := range channels {
+		attributes, _ := channel["attributes"].(map[string]string)
+		for _, group := range catchupAttributes {
+			own := false
+			for _, key := range group {
+				if _, ok := attributes[key]; ok {
+					own = true
+				}
+			}
+			for _, key := range group {
+				if value := header[key]; value != "" && !own {
+					if attributes == nil {
+						attributes = make(map[string]string)
+						channel["attributes"] = attributes
+					}
+					attributes[key] = value
+				}
+			}
+		}
+	}
+}
+
+// Catchup - The catch-up metadata of a channel.
+type Catchup struct {
+	// Mode is one of CatchupDefault, CatchupAppend, CatchupShift, CatchupFlussonic or CatchupXC.
+	Mode string
+	// Source is the catchup-source template.
+	Source string
+	// Days is the number of days of archive, 0 if unknown.
+	Days int
+}
+
+// ChannelCatchup returns the catch-up metadata of channel from its catchup (or catchup-type),
+// catchup-source and catchup-days (or timeshift) attributes. Modes like "flussonic-hls" and
+// "fs" are normalized. It returns false if the channel has no catch-up.
+func ChannelCatchup(channel Channel) (Catchup, bool) {
+	attributes, _ := channel["attributes"].(map[string]string)
+	mode := strings.ToLower(strings.TrimSpace(attributes["catchup"]))
+	if mode == "" {
+		mode = strings.ToLower(strings.TrimSpace(attributes["catchup-type"]))
+	}
+	catchup := Catchup{Source: strings.TrimSpace(attributes["catchup-source"])}
+	days := attributes["catchup-days"]
+	if days == "" {
+		days = attributes["timeshift"]
+	}
+	catchup.Days, _ = strconv.Atoi(strings.TrimSpace(days))
+	switch {
+	case mode == "" && catchup.Source != "":
+		catchup.Mode = CatchupDefault
+	case mode == "" && days != "":
+		// timeshift without catchup is the shift mode
+		catchup.Mode = CatchupShift
+	case mode == CatchupDefault, mode == CatchupAppend, mode == CatchupXC:
+		catchup.Mode = mode
+	case mode == CatchupShift, mode == "timeshift":
+		catchup.Mode = CatchupShift
+	case mode == "fs", strings.HasPrefix(mode, CatchupFlussonic):
+		catchup.Mode = CatchupFlussonic
+	default:
+		return catchup, false
+	}
+	return catchup, true
+}
+
+var (
+	catchupPlaceholderRegex = regexp.MustCompile(`\$?\{([a-zA-Z-]+)(?::([^}]*))?\}`)
+	flussonicURLRegex       = regexp.MustCompile(`^(https?://[^/]+)/(.*)/([^/]*)(mpegts|\.m3u8)(\?.+=.+)?$`)
+	xcURLRegex              = regexp.MustCompile(`^(https?://[^/]+)/(?:live/)?([^/]+)/([^/]+)/([^/.]+)(\.\w+)?$`)
+)
+
+// formatCatchupTime formats t with a layout of Y, m, d, H, M and S characters, eg. "Y-m-d:H-M".
+func formatCatchupTime(t time.Time, layout string) string {
+	replacer := strings.NewReplacer(
+		"Y", fmt.Sprintf("%04d", t.Year()), "m", fmt.Sprintf("%02d", int(t.Month())),
+		"d", fmt.Sprintf("%02d", t.Day()), "H", fmt.Sprintf("%02d", t.Hour()),
+		"M", fmt.Sprintf("%02d", t.Minute()), "S", fmt.Sprintf("%02d", t.Second()),
+	)
+	return replacer.Replace(layout)
+}
+
+// expandCatchup replaces the placeholders of template:
+//   - {utc}, ${start}, {utcend}, ${end}, {lutc}, ${now}, ${timestamp}: unix times of start, end and now.
+//     With a layout like {utc:Y-m-d} or ${start:YmdHMS} the time is formatted instead.
+//   - {Y}, {m}, {d}, {H}, {M}, {S}: parts of the start time.
+//   - {duration}, ${duration}: duration in seconds, {duration:60} divides it, eg. minutes.
+//   - {offset}, ${offset}: seconds from start to now, {offset:60} divides it.
+func expandCatchup(template string, start time.Time, duration time.Duration, now time.Time) string {
+	end := start.Add(duration)
+	return catchupPlaceholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		match := catchupPlaceholderRegex.FindStringSubmatch(placeholder)
+		name, arg := match[1], match[2]
+		var t time.Time
+		switch name {
+		case "utc", "start":
+			t = start
+		case "utcend", "end":
+			t = end
+		case "lutc", "now", "timestamp":
+			t = now
+		case "Y", "m", "d", "H", "M", "S":
+			return formatCatchupTime(start, name)
+		case "duration", "offset":
+			seconds := int64(duration / time.Second)
+			if name == "offset" {
+				seconds = int64(now.Sub(start) / time.Second)
+			}
+			if divisor, err := strconv.ParseInt(arg, 10, 64); err == nil && divisor > 0 {
+				seconds /= divisor
+			}
+			return strconv.FormatInt(seconds, 10)
+		default:
+			return placeholder
+		}
+		if arg != "" {
+			return formatCatchupTime(t, arg)
+		}
+		return strconv.FormatInt(t.Unix(), 10)
+	})
+}
+
+// CatchupURL returns the archive URL of channel for the programme starting at start and lasting duration.
+// Date placeholders use the location of start, pass start.UTC() or start.In(location) as the provider expects.
+//
+// Parameters:
+//   - channel: Channel with catch-up attributes, see ChannelCatchup.
+//   - start: Start time of the programme.
+//   - duration: Duration of the programme.
+//
+// It returns an error if the channel has no catch-up, start is outside the catch-up days or
+// the stream URL doesn't fit the mode.
+func CatchupURL(channel Channel, start time.Time, duration time.Duration) (string, error) {
+	return catchupURL(channel, start, duration, time.Now())
+}
+
+func catchupURL(channel Channel, start time.Time, duration time.Duration, now time.Time) (string, error) {
+	catchup, ok := ChannelCatchup(channel)
+	if !ok {
+		return "", fmt.Errorf("channel has no catch-up")
+	}
+	if catchup.Days > 0 && start.Before(now.AddDate(0, 0, -catchup.Days)) {
+		return "", fmt.Errorf("start %s is older than %d catch-up days", start.Format(time.RFC3339), catchup.Days)
+	}
+	streamURL, _ := channel["url"].(string)
+	var template string
+	switch catchup.Mode {
+	case CatchupDefault:
+		template = catchup.Source
+	case CatchupAppend:
+		if catchup.Source != "" {
+			template = streamURL + catchup.Source
+		}
+	case CatchupShift:
+		separator := "?"
+		if strings.Contains(streamURL, "?") {
+			separator = "&"
+		}
+		template = streamURL + separator + "utc={utc}&lutc={lutc}"
+	case CatchupFlussonic:
+		match := flussonicURLRegex.FindStringSubmatch(streamURL)
+		if match == nil {
+			return "", fmt.Errorf("stream url %q is not a flussonic url", streamURL)
+		}
+		if match[4] == "mpegts" {
+			template = match[1] + "/" + match[2] + "/timeshift_abs-{utc}.ts" + match[5]
+		} else {
+			template = match[1] + "/" + match[2] + "/" + match[3] + "-{utc}-{duration}.m3u8" + match[5]
+		}
+	case CatchupXC:
+		match := xcURLRegex.FindStringSubmatch(streamURL)
+		if match == nil {
+			return "", fmt.Errorf("stream url %q is not an xtream codes url", streamURL)
+		}
+		extension := ".ts"
+		if match[5] == ".m3u8" {
+			extension = ".m3u8"
+		}
+		template = match[1] + "/timeshift/" + match[2] + "/" + match[3] + "/{duration:60}/{Y}-{m}-{d}:{H}-{M}/" + match[4] + extension
+	}
+	if template == "" {
+		return "", fmt.Errorf("catch-up mode %s needs catchup-source", catchup.Mode)
+	}
+	return expandCatchup(template, start, duration, now), nil
+}
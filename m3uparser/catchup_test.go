@@ -0,0 +1,91 @@
+package m3uparser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCatchup(t *testing.T) {
+	parser := M3uParser{}
+	parser.ParseM3u(`#EXTM3U catchup="shift" catchup-days="3"
+#EXTINF:-1 tvg-id="a",Inherited
+http://example.com/a.m3u8
+#EXTINF:-1 catchup="append" catchup-source="?start={utc}",Own mode
+http://example.com/b.m3u8
+#EXTINF:-1 catchup-type="flussonic-hls" catchup-days="7",Alias
+http://example.com/c/index.m3u8`, false, false)
+	streams := parser.GetStreamsSlice()
+	expected := []Catchup{
+		{Mode: CatchupShift, Days: 3},
+		{Mode: CatchupAppend, Source: "?start={utc}", Days: 3},
+		{Mode: CatchupFlussonic, Days: 7},
+	}
+	for i, stream := range streams {
+		catchup, ok := ChannelCatchup(stream)
+		if !ok || catchup != expected[i] {
+			t.Errorf("%s: expected %+v, got %+v", stream["title"], expected[i], catchup)
+		}
+	}
+	if _, ok := ChannelCatchup(Channel{"url": "http://example.com"}); ok {
+		t.Error("Expected no catch-up without attributes")
+	}
+}
+
+func TestCatchupURL(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := start.Add(2 * time.Hour)
+	duration := time.Hour
+	tests := []struct {
+		url        string
+		attributes map[string]string
+		expected   string
+	}{
+		{"http://example.com/live.m3u8", map[string]string{"catchup": "default", "catchup-source": "http://example.com/archive/{Y}/{m}/{d}/{H}{M}{S}.m3u8?d={duration:60}"},
+			"http://example.com/archive/2024/01/02/030405.m3u8?d=60"},
+		{"http://example.com/live.m3u8", map[string]string{"catchup-source": "http://example.com/archive?s=${start}&e=${end}&o={offset:60}&t={utc:Y-m-d H:M}"},
+			"http://example.com/archive?s=1704164645&e=1704168245&o=120&t=2024-01-02 03:04"},
+		{"http://example.com/live.m3u8?token=1", map[string]string{"catchup": "append", "catchup-source": "&utc={utc}&lutc={lutc}"},
+			"http://example.com/live.m3u8?token=1&utc=1704164645&lutc=1704171845"},
+		{"http://example.com/live.m3u8", map[string]string{"catchup": "shift"},
+			"http://example.com/live.m3u8?utc=1704164645&lutc=1704171845"},
+		{"http://example.com/live.m3u8?a=b", map[string]string{"timeshift": "2"},
+			"http://example.com/live.m3u8?a=b&utc=1704164645&lutc=1704171845"},
+		{"http://list.tv:8888/325/index.m3u8?token=secret", map[string]string{"catchup": "flussonic"},
+			"http://list.tv:8888/325/index-1704164645-3600.m3u8?token=secret"},
+		{"http://ch01.example.net/151/mpegts?token=my_token", map[string]string{"catchup": "fs"},
+			"http://ch01.example.net/151/timeshift_abs-1704164645.ts?token=my_token"},
+		{"http://example.com:8080/live/user/pass/1234.m3u8", map[string]string{"catchup": "xc"},
+			"http://example.com:8080/timeshift/user/pass/60/2024-01-02:03-04/1234.m3u8"},
+		{"http://example.com:8080/user/pass/1234", map[string]string{"catchup": "xc"},
+			"http://example.com:8080/timeshift/user/pass/60/2024-01-02:03-04/1234.ts"},
+	}
+	for _, test := range tests {
+		channel := Channel{"url": test.url, "attributes": test.attributes}
+		archiveURL, err := catchupURL(channel, start, duration, now)
+		if err != nil {
+			t.Errorf("%v: %v", test.attributes, err)
+		} else if archiveURL != test.expected {
+			t.Errorf("%v: expected %s, got %s", test.attributes, test.expected, archiveURL)
+		}
+	}
+}
+
+func TestCatchupURLErrors(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		channel Channel
+		err     string
+	}{
+		{Channel{"url": "http://example.com/a"}, "no catch-up"},
+		{Channel{"url": "http://example.com/a", "attributes": map[string]string{"catchup": "shift", "catchup-days": "1"}}, "older than 1 catch-up days"},
+		{Channel{"url": "http://example.com/a", "attributes": map[string]string{"catchup": "append"}}, "needs catchup-source"},
+		{Channel{"url": "http://example.com/a", "attributes": map[string]string{"catchup": "flussonic"}}, "not a flussonic url"},
+		{Channel{"url": "http://example.com/a", "attributes": map[string]string{"catchup": "xc"}}, "not an xtream codes url"},
+	}
+	for _, test := range tests {
+		if _, err := catchupURL(test.channel, start, time.Hour, start.AddDate(0, 0, 2)); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v: expected error %q, got %v", test.channel, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package m3uparser
+
+// Collection - An immutable, ordered view of channels.
+//
+// Operations return a new collection and never modify the receiver, so they can be chained:
+//
+//	view := parser.Collection().Where(`status == "GOOD"`).Sort(SortKey{Key: "title"})
+//	if err := view.Err(); err != nil { ... }
+//
+// An operation given invalid input returns a collection holding the error. Following operations
+// are skipped and the error is reported by Err. Channels are shared between collections and must
+// not be modified.
+type Collection struct {
+	channels []Channel
+	err      error
+}
+
+// NewCollection returns a collection of channels.
+func NewCollection(channels []Channel) Collection {
+	copied := make([]Channel, len(channels))
+	copy(copied, channels)
+	return Collection{channels: copied}
+}
+
+// Err returns the error of the first failed operation leading to this collection.
+func (c Collection) Err() error {
+	return c.err
+}
+
+// Len returns the number of channels.
+func (c Collection) Len() int {
+	return len(c.channels)
+}
+
+// At returns the channel at index i.
+func (c Collection) At(i int) Channel {
+	return c.channels[i]
+}
+
+// Channels returns a copy of the channels slice.
+func (c Collection) Channels() []Channel {
+	copied := make([]Channel, len(c.channels))
+	copy(copied, c.channels)
+	return copied
+}
+
+// with returns a collection of channels keeping the error of c.
+func (c Collection) with(channels []Channel, err error) Collection {
+	if err != nil {
+		return Collection{channels: c.channels, err: err}
+	}
+	return Collection{channels: channels}
+}
+
+// Filter retrieves or removes channels using filter/s on key. See M3uParser.FilterWith.
+func (c Collection) Filter(key string, filters []string, retrieve bool, opts FilterOptions) Collection {
+	if c.err != nil || len(filters) == 0 {
+		return c
+	}
+	return c.with(filterChannels(c.channels, key, filters, retrieve, opts))
+}
+
+// Where retrieves channels matching a query expression. See Query.
+func (c Collection) Where(expr string) Collection {
+	if c.err != nil {
+		return c
+	}
+	query, err := CompileQuery(expr)
+	if err != nil {
+		return c.with(nil, err)
+	}
+	return c.Match(query)
+}
+
+// Match retrieves channels matching a compiled query.
+func (c Collection) Match(query *Query) Collection {
+	if c.err != nil {
+		return c
+	}
+	return Collection{channels: query.filterChannels(c.channels)}
+}
+
+// Sort sorts channels by keys. See M3uParser.SortByKeys.
+func (c Collection) Sort(keys ...SortKey) Collection {
+	if c.err != nil {
+		return c
+	}
+	return c.with(sortChannels(c.channels, keys))
+}
+
+// Dedupe removes duplicate channels. See M3uParser.Dedupe.
+func (c Collection) Dedupe(opts DedupeOptions) Collection {
+	if c.err != nil {
+		return c
+	}
+	return Collection{channels: dedupeChannels(c.channels, opts)}
+}
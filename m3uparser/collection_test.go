@@ -0,0 +1,79 @@
+package m3uparser
+
+import (
+	"testing"
+)
+
+func collectionTitles(channels []Channel) []string {
+	titles := make([]string, len(channels))
+	for i, channel := range channels {
+		titles[i], _ = channel["title"].(string)
+	}
+	return titles
+}
+
+func newHistoryParser() *M3uParser {
+	parser := &M3uParser{}
+	parser.ParseM3u(`#EXTM3U
+#EXTINF:-1 group-title="News",Channel 10
+http://example.com/10.m3u8
+#EXTINF:-1 group-title="Sports",Channel 2
+http://example.com/2.m3u8
+#EXTINF:-1 group-title="News",Channel 1
+http://example.com/1.m3u8
+#EXTINF:-1 group-title="News",Channel 1
+http://example.com/1.m3u8`, false, false)
+	return parser
+}
+
+func TestCollectionChaining(t *testing.T) {
+	parser := newHistoryParser()
+	original := parser.Collection()
+
+	view := original.Where(`category == "News"`).Dedupe(DedupeOptions{}).Sort(SortKey{Key: "title"})
+	if err := view.Err(); err != nil {
+		t.Fatal(err)
+	}
+	assertTitles(t, "view", collectionTitles(view.Channels()), "Channel 1", "Channel 10")
+	assertTitles(t, "original", collectionTitles(original.Channels()), "Channel 10", "Channel 2", "Channel 1", "Channel 1")
+
+	failed := original.Where(`category ==`).Sort(SortKey{Key: "title"})
+	if failed.Err() == nil {
+		t.Error("Expected error to be kept through the chain")
+	}
+	if err := parser.Apply(failed); err == nil {
+		t.Error("Expected Apply to return the collection error")
+	}
+	if len(parser.GetStreamsSlice()) != 4 {
+		t.Error("Failed collection must not be applied")
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	parser := newHistoryParser()
+	if parser.Undo() {
+		t.Error("Expected nothing to undo after parsing")
+	}
+
+	parser.SortBy("title", true)
+	parser.RetrieveByCategory([]string{"news"})
+	assertTitles(t, "after operations", collectionTitles(parser.GetStreamsSlice()), "Channel 1", "Channel 1", "Channel 10")
+
+	parser.Undo()
+	assertTitles(t, "undo filter", collectionTitles(parser.GetStreamsSlice()), "Channel 1", "Channel 1", "Channel 2", "Channel 10")
+	parser.Undo()
+	assertTitles(t, "undo sort", collectionTitles(parser.GetStreamsSlice()), "Channel 10", "Channel 2", "Channel 1", "Channel 1")
+	parser.Redo()
+	parser.Redo()
+	assertTitles(t, "redo", collectionTitles(parser.GetStreamsSlice()), "Channel 1", "Channel 1", "Channel 10")
+	if parser.Redo() {
+		t.Error("Expected nothing to redo")
+	}
+
+	parser.GetRandomStream(true)
+	parser.ResetOperations()
+	assertTitles(t, "reset", collectionTitles(parser.GetStreamsSlice()), "Channel 10", "Channel 2", "Channel 1", "Channel 1")
+	parser.Undo()
+	parser.Undo()
+	assertTitles(t, "undo reset and shuffle", collectionTitles(parser.GetStreamsSlice()), "Channel 1", "Channel 1", "Channel 10")
+}
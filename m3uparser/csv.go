@@ -0,0 +1,193 @@
+package m3uparser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultCSVColumns are the columns written when CSVOptions.Columns is empty.
+var DefaultCSVColumns = []string{
+	"title", "url", "category", "logo", "language",
+	"tvg.id", "tvg.name", "tvg.url", "country.code", "country.name", "status",
+}
+
+// csvListSeparator joins the elements of list values like alternatives in a cell.
+const csvListSeparator = "|"
+
+// CSVOptions - Options for reading and writing CSV/TSV.
+type CSVOptions struct {
+	// Columns are the key paths written as columns, see Path. eg. "attributes.tvg-chno".
+	// Defaults to DefaultCSVColumns. Reading uses the header row instead.
+	Columns []string
+	// Comma is the field delimiter. Defaults to ',', use '\t' for TSV.
+	Comma rune
+}
+
+func (opts CSVOptions) comma() rune {
+	if opts.Comma == 0 {
+		return ','
+	}
+	return opts.Comma
+}
+
+// csvColumns returns the default columns followed by a column for every extra attribute of channels.
+func csvColumns(channels []Channel) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, channel := range channels {
+		attributes, _ := channel["attributes"].(map[string]string)
+		for key := range attributes {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	columns := append([]string{}, DefaultCSVColumns...)
+	for _, key := range keys {
+		columns = append(columns, "attributes."+strings.ReplaceAll(key, ".", `\.`))
+	}
+	return columns
+}
+
+// WriteCSV writes the channels as CSV with a header row of column key paths.
+// List values are joined with "|".
+func (c Collection) WriteCSV(w io.Writer, opts CSVOptions) error {
+	if c.err != nil {
+		return c.err
+	}
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+	paths := make([]Path, len(columns))
+	for i, column := range columns {
+		path, err := ParsePath(column)
+		if err != nil {
+			return err
+		}
+		paths[i] = path
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.comma()
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(paths))
+	for _, channel := range c.channels {
+		for i, path := range paths {
+			record[i] = ""
+			if value, ok := path.Lookup(channel); ok && value != nil {
+				values := make([]string, 0, 1)
+				for _, v := range fieldValues(value) {
+					values = append(values, fmt.Sprintf("%v", v))
+				}
+				record[i] = strings.Join(values, csvListSeparator)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadCSV reads channels from CSV with a header row of column key paths, eg. as written by WriteCSV.
+// Columns are set with Path.Set, empty cells are skipped and rows without url are an error.
+// Rows are numbered from 1 for the header in errors.
+// The "alternatives" column is split on "|". Country names are filled in from country codes.
+func ReadCSV(r io.Reader, opts CSVOptions) ([]Channel, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.comma()
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]Path, len(header))
+	for i, column := range header {
+		path, err := ParsePath(strings.TrimSpace(column))
+		if err != nil {
+			return nil, fmt.Errorf("csv column %d: %v", i+1, err)
+		}
+		paths[i] = path
+	}
+
+	var channels []Channel
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		channel := make(Channel)
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if i >= len(paths) || cell == "" {
+				continue
+			}
+			var value interface{} = cell
+			if paths[i].String() == "alternatives" {
+				value = strings.Split(cell, csvListSeparator)
+			}
+			if err := paths[i].Set(channel, value); err != nil {
+				return nil, fmt.Errorf("csv row %d: %v", row, err)
+			}
+		}
+		if len(channel) == 0 {
+			continue
+		}
+		if streamURL, _ := channel["url"].(string); streamURL == "" {
+			return nil, fmt.Errorf("csv row %d: missing url", row)
+		}
+		if country, ok := channel["country"].(map[string]string); ok && country["code"] != "" && country["name"] == "" {
+			if info := countryClient.MapByAlpha2(strings.ToUpper(country["code"])); info != nil {
+				country["name"] = info.Name
+			}
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// ParseCSV parses CSV/TSV content of local file/URL or raw CSV content.
+// It reads a sheet with a header row of column key paths (eg. title, url, tvg.id, country.code,
+// attributes.tvg-chno) into streams information which can then be saved in any format.
+// Any previously parsed streams information is replaced.
+//
+// Parameters:
+//   - source: URL, file path or raw CSV content.
+//   - checkLive: Boolean flag to check if stream URLs are accessible and working
+//   - opts: Comma to read TSV or other delimiters.
+func (p *M3uParser) ParseCSV(source string, checkLive bool, opts CSVOptions) error {
+	p.setup(checkLive, false)
+	content, err := p.readSource(source)
+	if err != nil {
+		return err
+	}
+	streams, err := ReadCSV(strings.NewReader(content), opts)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		log.Infoln("No content to parse!!!")
+	}
+	if p.CheckLive {
+		p.checkChannels(streams)
+	}
+	p.load(streams)
+	return nil
+}
@@ -0,0 +1,76 @@
+package m3uparser
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	parser := M3uParser{}
+	parser.ParseM3u(`#EXTM3U
+#EXTINF:-1 tvg-id="a.np" tvg-chno="7" group-title="News;Nepal",Channel "A", HD
+http://example.com/a.m3u8
+#EXTINF:-1 tvg-name="B" tvg-logo="http://example.com/b.png",Channel B
+http://example.com/b.m3u8`, false, false)
+
+	for _, format := range []string{"csv", "tsv"} {
+		fileName := "roundtrip_test." + format
+		parser.ToFile(fileName)
+		content, err := ioutil.ReadFile(fileName)
+		os.Remove(fileName)
+		if err != nil {
+			t.Fatalf("%s: file is not saved: %v", format, err)
+		}
+		if !strings.Contains(strings.SplitN(string(content), "\n", 2)[0], "attributes.tvg-chno") {
+			t.Errorf("%s: expected attribute column in header, got %s", format, content)
+		}
+
+		opts := CSVOptions{}
+		if format == "tsv" {
+			opts.Comma = '\t'
+		}
+		decoded := M3uParser{}
+		if err := decoded.ParseCSV(string(content), false, opts); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if !reflect.DeepEqual(decoded.GetStreamsSlice(), parser.GetStreamsSlice()) {
+			t.Errorf("%s: expected %v, got %v", format, parser.GetStreamsSlice(), decoded.GetStreamsSlice())
+		}
+	}
+}
+
+func TestWriteCSVColumns(t *testing.T) {
+	collection := NewCollection([]Channel{
+		{"title": "A", "url": "http://example.com/a", "alternatives": []string{"http://example.com/a1", "http://example.com/a2"}},
+	})
+	var sb strings.Builder
+	if err := collection.WriteCSV(&sb, CSVOptions{Columns: []string{"title", "alternatives", "tvg.id"}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "title,alternatives,tvg.id\nA,http://example.com/a1|http://example.com/a2,\n"
+	if sb.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, sb.String())
+	}
+	if err := collection.WriteCSV(&sb, CSVOptions{Columns: []string{"a..b"}}); err == nil {
+		t.Error("Expected error for invalid column")
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	if _, err := ReadCSV(strings.NewReader("title,url\nA,\n"), CSVOptions{}); err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("Expected missing url error on row 2, got %v", err)
+	}
+	if _, err := ReadCSV(strings.NewReader("title,alternatives[0]\nA,b\n"), CSVOptions{}); err == nil {
+		t.Error("Expected error for indexed column")
+	}
+	channels, err := ReadCSV(strings.NewReader("title,url,alternatives\n,,\nA,http://a,http://b|http://c\n"), CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || !reflect.DeepEqual(channels[0]["alternatives"], []string{"http://b", "http://c"}) {
+		t.Errorf("Unexpected channels %v", channels)
+	}
+}
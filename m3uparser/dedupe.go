@@ -0,0 +1,203 @@
+package m3uparser
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DedupeStrategy - Strategy deciding when two channels are duplicates.
+type DedupeStrategy int
+
+const (
+	// DedupeByURL treats channels with exactly the same url as duplicates.
+	DedupeByURL DedupeStrategy = iota
+	// DedupeByNormalizedURL treats channels as duplicates when their urls are equal after
+	// lowercasing scheme and host, dropping default ports, fragments and trailing slashes
+	// and sorting query parameters.
+	DedupeByNormalizedURL
+	// DedupeByTvgID treats channels with the same tvg id as duplicates.
+	DedupeByTvgID
+	// DedupeByTitleCountry treats channels with the same normalized title and country code as duplicates.
+	DedupeByTitleCountry
+)
+
+// dedupeStrategyNames are the names of the strategies accepted by ParseDedupeStrategy.
+var dedupeStrategyNames = map[string]DedupeStrategy{
+	"url":            DedupeByURL,
+	"normalized-url": DedupeByNormalizedURL,
+	"tvg-id":         DedupeByTvgID,
+	"title-country":  DedupeByTitleCountry,
+}
+
+// ParseDedupeStrategy returns the strategy named url, normalized-url, tvg-id or title-country.
+func ParseDedupeStrategy(name string) (DedupeStrategy, error) {
+	strategy, ok := dedupeStrategyNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown dedupe strategy %q, want url, normalized-url, tvg-id or title-country", name)
+	}
+	return strategy, nil
+}
+
+// DedupeOptions - Options for Dedupe.
+type DedupeOptions struct {
+	// Strategy used to detect duplicates.
+	Strategy DedupeStrategy
+	// Merge keeps one channel per duplicate group with the urls of the other channels
+	// under the "alternatives" key. If false, the other channels are dropped.
+	Merge bool
+}
+
+var (
+	titleQualifierRegex = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+	titleNonWordRegex   = regexp.MustCompile(`[^\pL\pN]+`)
+)
+
+// normalizeTitle lowercases title and removes qualifiers like "(1080p)" or "[Geo-blocked]" and punctuation.
+func normalizeTitle(title string) string {
+	title = titleQualifierRegex.ReplaceAllString(strings.ToLower(title), " ")
+	return strings.TrimSpace(titleNonWordRegex.ReplaceAllString(title, " "))
+}
+
+// normalizeURL returns rawURL in a canonical form so that equivalent urls compare equal.
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
+
+// dedupeKey returns the key identifying duplicates of channel or "" if it can't be identified.
+func dedupeKey(channel Channel, strategy DedupeStrategy) string {
+	streamURL, _ := channel["url"].(string)
+	switch strategy {
+	case DedupeByNormalizedURL:
+		return normalizeURL(streamURL)
+	case DedupeByTvgID:
+		tvg, _ := channel["tvg"].(map[string]string)
+		return strings.ToLower(strings.TrimSpace(tvg["id"]))
+	case DedupeByTitleCountry:
+		title, _ := channel["title"].(string)
+		title = normalizeTitle(title)
+		if title == "" {
+			return ""
+		}
+		country, _ := channel["country"].(map[string]string)
+		return title + "|" + strings.ToUpper(country["code"])
+	default:
+		return streamURL
+	}
+}
+
+// preferChannel reports whether channel a should be kept over channel b.
+// GOOD channels are preferred over others and among GOOD channels the one with lower latency.
+func preferChannel(a, b Channel) bool {
+	aGood, bGood := a["status"] == "GOOD", b["status"] == "GOOD"
+	if aGood != bGood {
+		return aGood
+	}
+	aLatency, aOk := toFloat(a["latency"])
+	bLatency, bOk := toFloat(b["latency"])
+	if aOk && bOk {
+		return aLatency < bLatency
+	}
+	return aOk && !bOk
+}
+
+// dedupeChannels returns channels without duplicates. The kept channel of a group takes
+// the position of the group's first channel.
+func dedupeChannels(channels []Channel, opts DedupeOptions) []Channel {
+	var groups [][]Channel
+	index := make(map[string]int)
+	for _, channel := range channels {
+		key := dedupeKey(channel, opts.Strategy)
+		if key == "" {
+			groups = append(groups, []Channel{channel})
+			continue
+		}
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], channel)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []Channel{channel})
+	}
+
+	deduped := make([]Channel, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 1 {
+			deduped = append(deduped, group[0])
+			continue
+		}
+		ordered := make([]Channel, 0, len(group))
+		for _, channel := range group {
+			i := len(ordered)
+			for i > 0 && preferChannel(channel, ordered[i-1]) {
+				i--
+			}
+			ordered = append(ordered, nil)
+			copy(ordered[i+1:], ordered[i:])
+			ordered[i] = channel
+		}
+		if !opts.Merge {
+			deduped = append(deduped, ordered[0])
+			continue
+		}
+		merged := copyChannel(ordered[0])
+		primary, _ := merged["url"].(string)
+		seen := map[string]bool{primary: true}
+		var alternatives []string
+		for _, channel := range ordered {
+			urls := []string{}
+			if streamURL, ok := channel["url"].(string); ok {
+				urls = append(urls, streamURL)
+			}
+			if others, ok := channel["alternatives"].([]string); ok {
+				urls = append(urls, others...)
+			}
+			for _, streamURL := range urls {
+				if !seen[streamURL] {
+					seen[streamURL] = true
+					alternatives = append(alternatives, streamURL)
+				}
+			}
+		}
+		if len(alternatives) > 0 {
+			merged["alternatives"] = alternatives
+		}
+		deduped = append(deduped, merged)
+	}
+	return deduped
+}
+
+// Dedupe removes duplicate streams information.
+// It detects duplicates using the given strategy and either drops them or merges them into one
+// channel with the other urls under the "alternatives" key. When liveness data is present the
+// GOOD and fastest stream of a group is kept as the channel url.
+//
+// Parameters:
+//   - opts: Strategy to detect duplicates and whether to merge them.
+func (p *M3uParser) Dedupe(opts DedupeOptions) {
+	if p.isEmpty() {
+		log.Infof("No streams info to dedupe.")
+		return
+	}
+	before := len(p.streamsInfo)
+	p.Apply(p.Collection().Dedupe(opts))
+	log.Infof("Removed %d duplicate streams.", before-len(p.streamsInfo))
+}
@@ -0,0 +1,57 @@
+package m3uparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDedupeStrategies(t *testing.T) {
+	channels := []Channel{
+		{"title": "News (1080p)", "url": "http://Example.com:80/live/?b=2&a=1", "tvg": map[string]string{"id": "news.np"}, "country": map[string]string{"code": "NP"}},
+		{"title": "news [Geo-blocked]", "url": "http://example.com/live?a=1&b=2", "tvg": map[string]string{"id": "News.np"}, "country": map[string]string{"code": "np"}},
+		{"title": "News", "url": "http://example.com/live?a=1&b=2", "country": map[string]string{"code": "IN"}},
+		{"title": "Sports", "url": "http://example.com/sports"},
+	}
+
+	tests := []struct {
+		strategy DedupeStrategy
+		expected int
+	}{
+		{DedupeByURL, 3},
+		{DedupeByNormalizedURL, 2},
+		{DedupeByTvgID, 3},
+		{DedupeByTitleCountry, 3},
+	}
+	for _, test := range tests {
+		deduped := dedupeChannels(channels, DedupeOptions{Strategy: test.strategy})
+		if len(deduped) != test.expected {
+			t.Errorf("Strategy %d: expected %d channels, got %d", test.strategy, test.expected, len(deduped))
+		}
+	}
+}
+
+func TestDedupeMergePrefersGoodAndFastest(t *testing.T) {
+	parser := M3uParser{}
+	parser.streamsInfo = []Channel{
+		{"title": "News", "url": "http://a.example.com/news", "status": "BAD"},
+		{"title": "Sports", "url": "http://example.com/sports"},
+		{"title": "News", "url": "http://b.example.com/news", "status": "GOOD", "latency": int64(300)},
+		{"title": "News", "url": "http://c.example.com/news", "status": "GOOD", "latency": int64(100)},
+	}
+	parser.Dedupe(DedupeOptions{Strategy: DedupeByTitleCountry, Merge: true})
+
+	streams := parser.GetStreamsSlice()
+	if len(streams) != 2 {
+		t.Fatalf("Expected 2 channels, got %d", len(streams))
+	}
+	if streams[0]["url"] != "http://c.example.com/news" {
+		t.Errorf("Expected fastest GOOD url to be kept, got %v", streams[0]["url"])
+	}
+	expected := []string{"http://b.example.com/news", "http://a.example.com/news"}
+	if !reflect.DeepEqual(streams[0]["alternatives"], expected) {
+		t.Errorf("Expected alternatives %v, got %v", expected, streams[0]["alternatives"])
+	}
+	if streams[1]["title"] != "Sports" {
+		t.Errorf("Expected order to be kept, got %v", streams[1])
+	}
+}
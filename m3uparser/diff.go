@@ -0,0 +1,322 @@
+package m3uparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DiffIdentity - Key identifying the same channel in two playlists.
+type DiffIdentity int
+
+const (
+	// DiffByTvgID matches channels with the same tvg id, ignoring case.
+	DiffByTvgID DiffIdentity = iota
+	// DiffByURL matches channels with the same url after normalization, see DedupeByNormalizedURL.
+	DiffByURL
+	// DiffByTitle matches channels with the same normalized title, ignoring qualifiers like "(1080p)".
+	DiffByTitle
+)
+
+// diffIdentityNames are the names of the identities accepted by ParseDiffIdentity.
+var diffIdentityNames = map[string]DiffIdentity{
+	"tvg-id": DiffByTvgID,
+	"url":    DiffByURL,
+	"title":  DiffByTitle,
+}
+
+// ParseDiffIdentity returns the identity named tvg-id, url or title.
+func ParseDiffIdentity(name string) (DiffIdentity, error) {
+	identity, ok := diffIdentityNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown diff identity %q, want tvg-id, url or title", name)
+	}
+	return identity, nil
+}
+
+// key returns the value of the identity of channel or "" if channel doesn't have it.
+func (identity DiffIdentity) key(channel Channel) string {
+	switch identity {
+	case DiffByTvgID:
+		return dedupeKey(channel, DedupeByTvgID)
+	case DiffByURL:
+		return dedupeKey(channel, DedupeByNormalizedURL)
+	case DiffByTitle:
+		title, _ := channel["title"].(string)
+		return normalizeTitle(title)
+	}
+	return ""
+}
+
+// DefaultDiffFields are the key paths compared by Diff if DiffOptions.Fields is empty.
+var DefaultDiffFields = []string{"title", "url", "logo", "category", "status", "tvg.id", "country.code", "language"}
+
+// DiffOptions - Options for Diff.
+type DiffOptions struct {
+	// Identities tried in order to match channels, defaults to tvg-id then url. Channels left
+	// unmatched by an identity, eg. without a tvg id, are matched by the next one.
+	Identities []DiffIdentity
+	// Fields are the key paths compared between matched channels, defaults to DefaultDiffFields.
+	Fields []string
+}
+
+// FieldChange - A field whose value differs between matched channels.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ChannelChange - A channel present in both playlists with changed fields.
+type ChannelChange struct {
+	Old     Channel
+	New     Channel
+	Changes []FieldChange
+}
+
+// ChangeSet - The differences between two playlists returned by Diff.
+type ChangeSet struct {
+	// Added are the channels only in the current playlist, in its order.
+	Added []Channel
+	// Removed are the channels only in the previous playlist, in its order.
+	Removed []Channel
+	// Changed are the matched channels with changed fields, in the order of the previous playlist.
+	Changed []ChannelChange
+	// Unchanged is the number of matched channels without changes.
+	Unchanged int
+}
+
+// Diff compares the previous and current parse of a playlist, eg. to review changes of a provider.
+// Channels are matched by the identities of opts, channels with the same key are matched in order.
+//
+// Parameters:
+//   - previous: The channels before.
+//   - current: The channels after.
+//   - opts: Identities matching channels and the fields compared.
+//
+// It returns an error if a collection has one or a field is not a valid key path.
+func Diff(previous, current Collection, opts DiffOptions) (ChangeSet, error) {
+	if previous.err != nil {
+		return ChangeSet{}, previous.err
+	}
+	if current.err != nil {
+		return ChangeSet{}, current.err
+	}
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = DefaultDiffFields
+	}
+	paths := make([]Path, len(fields))
+	for i, field := range fields {
+		path, err := ParsePath(field)
+		if err != nil {
+			return ChangeSet{}, err
+		}
+		paths[i] = path
+	}
+	identities := opts.Identities
+	if len(identities) == 0 {
+		identities = []DiffIdentity{DiffByTvgID, DiffByURL}
+	}
+
+	// matches[i] is the index of the current channel matched with previous channel i, or -1
+	matches := make([]int, len(previous.channels))
+	for i := range matches {
+		matches[i] = -1
+	}
+	matched := make([]bool, len(current.channels))
+	for _, identity := range identities {
+		unmatched := make(map[string][]int)
+		for j, channel := range current.channels {
+			if key := identity.key(channel); !matched[j] && key != "" {
+				unmatched[key] = append(unmatched[key], j)
+			}
+		}
+		for i, channel := range previous.channels {
+			key := identity.key(channel)
+			if matches[i] >= 0 || key == "" || len(unmatched[key]) == 0 {
+				continue
+			}
+			j := unmatched[key][0]
+			unmatched[key] = unmatched[key][1:]
+			matches[i], matched[j] = j, true
+		}
+	}
+
+	var changes ChangeSet
+	for i, channel := range previous.channels {
+		if matches[i] < 0 {
+			changes.Removed = append(changes.Removed, channel)
+			continue
+		}
+		change := ChannelChange{Old: channel, New: current.channels[matches[i]]}
+		for k, path := range paths {
+			oldValue := strings.Join(pathValues(path)(change.Old), ", ")
+			newValue := strings.Join(pathValues(path)(change.New), ", ")
+			if oldValue != newValue {
+				change.Changes = append(change.Changes, FieldChange{Field: fields[k], Old: oldValue, New: newValue})
+			}
+		}
+		if len(change.Changes) == 0 {
+			changes.Unchanged++
+			continue
+		}
+		changes.Changed = append(changes.Changed, change)
+	}
+	for j, channel := range current.channels {
+		if !matched[j] {
+			changes.Added = append(changes.Added, channel)
+		}
+	}
+	return changes, nil
+}
+
+// Len returns the number of added, removed and changed channels.
+func (s ChangeSet) Len() int {
+	return len(s.Added) + len(s.Removed) + len(s.Changed)
+}
+
+// channelLabel returns the title and url of channel.
+func channelLabel(channel Channel) string {
+	title, _ := channel["title"].(string)
+	streamURL, _ := channel["url"].(string)
+	return strings.TrimSpace(title + " " + streamURL)
+}
+
+// orNone returns value or "(none)" if it is empty.
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// WriteText writes the changes as "+ " lines of added, "- " lines of removed and "~ " lines of
+// changed channels followed by their field changes, and a summary line.
+func (s ChangeSet) WriteText(w io.Writer) error {
+	var lines []string
+	for _, channel := range s.Added {
+		lines = append(lines, "+ "+channelLabel(channel))
+	}
+	for _, channel := range s.Removed {
+		lines = append(lines, "- "+channelLabel(channel))
+	}
+	for _, change := range s.Changed {
+		lines = append(lines, "~ "+channelLabel(change.Old))
+		for _, field := range change.Changes {
+			lines = append(lines, fmt.Sprintf("    %s: %s -> %s", field.Field, orNone(field.Old), orNone(field.New)))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%d added, %d removed, %d changed, %d unchanged",
+		len(s.Added), len(s.Removed), len(s.Changed), s.Unchanged))
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// jsonChannelChange - The document representation of a ChannelChange.
+type jsonChannelChange struct {
+	Old     JSONChannel   `json:"old"`
+	New     JSONChannel   `json:"new"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// jsonChangeSet - The document representation of a ChangeSet.
+type jsonChangeSet struct {
+	Added     []JSONChannel       `json:"added"`
+	Removed   []JSONChannel       `json:"removed"`
+	Changed   []jsonChannelChange `json:"changed"`
+	Unchanged int                 `json:"unchanged"`
+}
+
+// WriteJSON writes the changes as an indented JSON object with added, removed and changed
+// channels in the JSON document representation and the number of unchanged channels.
+func (s ChangeSet) WriteJSON(w io.Writer) error {
+	document := jsonChangeSet{
+		Added:     []JSONChannel{},
+		Removed:   []JSONChannel{},
+		Changed:   []jsonChannelChange{},
+		Unchanged: s.Unchanged,
+	}
+	for _, channel := range s.Added {
+		document.Added = append(document.Added, newJSONChannel(channel))
+	}
+	for _, channel := range s.Removed {
+		document.Removed = append(document.Removed, newJSONChannel(channel))
+	}
+	for _, change := range s.Changed {
+		document.Changed = append(document.Changed, jsonChannelChange{
+			Old: newJSONChannel(change.Old), New: newJSONChannel(change.New), Changes: change.Changes,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(document)
+}
+
+// m3uEntry returns the #EXTINF and url lines of channel prefixed with prefix.
+func m3uEntry(channel Channel, prefix string) ([]string, error) {
+	var buf strings.Builder
+	if err := NewCollection([]Channel{channel}).WriteM3U(&buf); err != nil {
+		return nil, err
+	}
+	lines := strings.Split(buf.String(), "\n")[1:]
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return lines, nil
+}
+
+// WriteM3U writes the changes as a unified diff of M3U entries: the entries of changed channels
+// before and after, then the removed and added entries, each under a "@@ " header.
+func (s ChangeSet) WriteM3U(w io.Writer) error {
+	lines := []string{"--- previous", "+++ current"}
+	add := func(channel Channel, prefix string) error {
+		entry, err := m3uEntry(channel, prefix)
+		lines = append(lines, entry...)
+		return err
+	}
+	for _, change := range s.Changed {
+		fields := make([]string, len(change.Changes))
+		for i, field := range change.Changes {
+			fields[i] = field.Field
+		}
+		title, _ := change.Old["title"].(string)
+		lines = append(lines, fmt.Sprintf("@@ changed %s: %s @@", title, strings.Join(fields, ", ")))
+		if err := add(change.Old, "-"); err != nil {
+			return err
+		}
+		if err := add(change.New, "+"); err != nil {
+			return err
+		}
+	}
+	for _, channel := range s.Removed {
+		title, _ := channel["title"].(string)
+		lines = append(lines, "@@ removed "+title+" @@")
+		if err := add(channel, "-"); err != nil {
+			return err
+		}
+	}
+	for _, channel := range s.Added {
+		title, _ := channel["title"].(string)
+		lines = append(lines, "@@ added "+title+" @@")
+		if err := add(channel, "+"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// WriteFormat writes the changes as text, json or m3u.
+func (s ChangeSet) WriteFormat(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "text":
+		return s.WriteText(w)
+	case "json":
+		return s.WriteJSON(w)
+	case "m3u":
+		return s.WriteM3U(w)
+	}
+	return fmt.Errorf("unsupported diff format %q, want text, json or m3u", format)
+}
@@ -0,0 +1,140 @@
+package m3uparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	previous := NewCollection([]Channel{
+		{"title": "Kantipur TV", "url": "http://example.com/kantipur", "category": "News", "tvg": map[string]string{"id": "Kantipur.np"}, "status": "GOOD"},
+		{"title": "BBC News", "url": "http://example.com/bbc", "logo": "http://example.com/bbc.png", "tvg": map[string]string{"id": "BBCNews.uk"}},
+		{"title": "Sports 1", "url": "http://example.com/sports", "category": "Sports"},
+		{"title": "Movies", "url": "http://example.com/movies"},
+	})
+	current := NewCollection([]Channel{
+		{"title": "Music", "url": "http://example.com/music"},
+		{"title": "Kantipur HD", "url": "http://example.com/kantipur-hd", "category": "News", "tvg": map[string]string{"id": "kantipur.np"}, "status": "BAD"},
+		{"title": "BBC News", "url": "http://example.com/bbc", "logo": "http://example.com/bbc.png", "tvg": map[string]string{"id": "BBCNews.uk"}},
+		{"title": "Sports 1 (1080p)", "url": "HTTP://Example.com:80/sports/", "category": "Live Sports"},
+	})
+
+	changes, err := Diff(previous, current, DiffOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Added) != 1 || changes.Added[0]["title"] != "Music" {
+		t.Errorf("Added %v", changes.Added)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0]["title"] != "Movies" {
+		t.Errorf("Removed %v", changes.Removed)
+	}
+	if changes.Unchanged != 1 || len(changes.Changed) != 2 {
+		t.Fatalf("Expected 2 changed and 1 unchanged, got %d and %d", len(changes.Changed), changes.Unchanged)
+	}
+	expected := []FieldChange{
+		{Field: "title", Old: "Kantipur TV", New: "Kantipur HD"},
+		{Field: "url", Old: "http://example.com/kantipur", New: "http://example.com/kantipur-hd"},
+		{Field: "status", Old: "GOOD", New: "BAD"},
+		{Field: "tvg.id", Old: "Kantipur.np", New: "kantipur.np"},
+	}
+	if !reflect.DeepEqual(changes.Changed[0].Changes, expected) {
+		t.Errorf("Changes %v, want %v", changes.Changed[0].Changes, expected)
+	}
+	if fields := changes.Changed[1].Changes; len(fields) != 3 || fields[2].Field != "category" {
+		t.Errorf("Sports 1 changes %v", fields)
+	}
+
+	// matched by normalized title only, the changed urls are added and removed
+	changes, err = Diff(previous, current, DiffOptions{Identities: []DiffIdentity{DiffByTitle}, Fields: []string{"category"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Added) != 2 || len(changes.Removed) != 2 || len(changes.Changed) != 1 || changes.Unchanged != 1 {
+		t.Errorf("By title: %d added, %d removed, %d changed, %d unchanged",
+			len(changes.Added), len(changes.Removed), len(changes.Changed), changes.Unchanged)
+	}
+
+	if _, err := Diff(previous, current, DiffOptions{Fields: []string{"tvg..id"}}); err == nil {
+		t.Error("Expected an error for an invalid field")
+	}
+}
+
+func TestChangeSetFormats(t *testing.T) {
+	previous := NewCollection([]Channel{
+		{"title": "News", "url": "http://example.com/news", "status": "GOOD"},
+		{"title": "Movies", "url": "http://example.com/movies"},
+	})
+	current := NewCollection([]Channel{
+		{"title": "News", "url": "http://example.com/news", "status": "BAD"},
+		{"title": "Music", "url": "http://example.com/music"},
+	})
+	changes, err := Diff(previous, current, DiffOptions{Identities: []DiffIdentity{DiffByURL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text bytes.Buffer
+	if err := changes.WriteFormat(&text, "text"); err != nil {
+		t.Fatal(err)
+	}
+	expectedText := `+ Music http://example.com/music
+- Movies http://example.com/movies
+~ News http://example.com/news
+    status: GOOD -> BAD
+1 added, 1 removed, 1 changed, 0 unchanged
+`
+	if text.String() != expectedText {
+		t.Errorf("Text\n%s\nwant\n%s", text.String(), expectedText)
+	}
+
+	var m3u bytes.Buffer
+	if err := changes.WriteFormat(&m3u, "m3u"); err != nil {
+		t.Fatal(err)
+	}
+	expectedM3U := `--- previous
++++ current
+@@ changed News: status @@
+-#EXTINF:-1,News
+-http://example.com/news
++#EXTINF:-1,News
++http://example.com/news
+@@ removed Movies @@
+-#EXTINF:-1,Movies
+-http://example.com/movies
+@@ added Music @@
++#EXTINF:-1,Music
++http://example.com/music
+`
+	if m3u.String() != expectedM3U {
+		t.Errorf("M3U\n%s\nwant\n%s", m3u.String(), expectedM3U)
+	}
+
+	var buf bytes.Buffer
+	if err := changes.WriteFormat(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		Added   []JSONChannel
+		Changed []struct {
+			New     JSONChannel
+			Changes []FieldChange
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Added) != 1 || *document.Added[0].Title != "Music" {
+		t.Errorf("JSON added %v", document.Added)
+	}
+	if len(document.Changed) != 1 || *document.Changed[0].New.Status != "BAD" || document.Changed[0].Changes[0].New != "BAD" {
+		t.Errorf("JSON changed %s", buf.String())
+	}
+
+	if err := changes.WriteFormat(&buf, "xml"); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package m3uparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MatchMode - How a filter is matched against a value.
+type MatchMode int
+
+const (
+	// MatchContains matches values containing the filter.
+	MatchContains MatchMode = iota
+	// MatchExact matches values equal to the filter.
+	MatchExact
+	// MatchPrefix matches values starting with the filter.
+	MatchPrefix
+	// MatchRegex matches values against the filter as a regular expression.
+	MatchRegex
+)
+
+// ParseMatchMode returns the mode named contains, exact, prefix or regex.
+func ParseMatchMode(name string) (MatchMode, error) {
+	switch strings.ToLower(name) {
+	case "contains":
+		return MatchContains, nil
+	case "exact":
+		return MatchExact, nil
+	case "prefix":
+		return MatchPrefix, nil
+	case "regex":
+		return MatchRegex, nil
+	}
+	return 0, fmt.Errorf("unknown match mode %q, want contains, exact, prefix or regex", name)
+}
+
+// FilterOptions - Options for FilterWith.
+// The zero value matches values containing any of the filters, case-insensitive.
+type FilterOptions struct {
+	// Mode decides how each filter is matched against the value.
+	Mode MatchMode
+	// MatchAll requires all filters to match instead of any of them.
+	MatchAll bool
+	// CaseSensitive disables case-insensitive matching.
+	CaseSensitive bool
+}
+
+// matcher returns a function reporting whether a value matches filter.
+func (opts FilterOptions) matcher(filter string) (func(value string) bool, error) {
+	if opts.Mode == MatchRegex {
+		pattern := filter
+		if !opts.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %v", filter, err)
+		}
+		return re.MatchString, nil
+	}
+	if !opts.CaseSensitive {
+		filter = strings.ToLower(filter)
+	}
+	return func(value string) bool {
+		if !opts.CaseSensitive {
+			value = strings.ToLower(value)
+		}
+		switch opts.Mode {
+		case MatchExact:
+			return value == filter
+		case MatchPrefix:
+			return strings.HasPrefix(value, filter)
+		}
+		return strings.Contains(value, filter)
+	}, nil
+}
+
+// filterChannels returns the channels retrieved or kept by filters on key.
+func filterChannels(channels []Channel, key string, filters []string, retrieve bool, opts FilterOptions) ([]Channel, error) {
+	path, err := ParsePath(key)
+	if err != nil {
+		return nil, err
+	}
+	matchers := make([]func(string) bool, len(filters))
+	for i, filter := range filters {
+		matcher, err := opts.matcher(filter)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = matcher
+	}
+
+	filtered := []Channel{}
+	for _, channel := range channels {
+		var matched bool
+		if value, ok := path.Lookup(channel); ok {
+			matched = matchValues(fieldValues(value), matchers, opts.MatchAll)
+		}
+		if matched == retrieve {
+			filtered = append(filtered, channel)
+		}
+	}
+	return filtered, nil
+}
+
+// matchValues reports whether any (or all with matchAll) matchers match any of values.
+func matchValues(values []interface{}, matchers []func(string) bool, matchAll bool) bool {
+	for _, matcher := range matchers {
+		matched := false
+		for _, value := range values {
+			if matcher(fmt.Sprintf("%v", value)) {
+				matched = true
+				break
+			}
+		}
+		if matched && !matchAll {
+			return true
+		}
+		if !matched && matchAll {
+			return false
+		}
+	}
+	return matchAll
+}
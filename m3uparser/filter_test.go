@@ -0,0 +1,77 @@
+package m3uparser
+
+import (
+	"testing"
+)
+
+func filterTitles(t *testing.T, channels []Channel, key string, filters []string, retrieve bool, opts FilterOptions) []string {
+	t.Helper()
+	filtered, err := filterChannels(channels, key, filters, retrieve, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	titles := []string{}
+	for _, channel := range filtered {
+		titles = append(titles, channel["title"].(string))
+	}
+	return titles
+}
+
+func TestFilterWith(t *testing.T) {
+	channels := []Channel{
+		{"title": "A", "category": "Sports News", "tvg": map[string]string{"id": "a.np"}},
+		{"title": "B", "category": "News"},
+		{"title": "C", "category": "Sports"},
+		{"title": "D", "category": "Movies"},
+		{"title": "E"},
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		filters  []string
+		retrieve bool
+		opts     FilterOptions
+		expected []string
+	}{
+		{"retrieve any without duplicates", "category", []string{"news", "sport"}, true, FilterOptions{}, []string{"A", "B", "C"}},
+		{"remove any keeps missing key", "category", []string{"news", "sport"}, false, FilterOptions{}, []string{"D", "E"}},
+		{"retrieve all", "category", []string{"news", "sport"}, true, FilterOptions{MatchAll: true}, []string{"A"}},
+		{"remove all", "category", []string{"news", "sport"}, false, FilterOptions{MatchAll: true}, []string{"B", "C", "D", "E"}},
+		{"exact", "category", []string{"news"}, true, FilterOptions{Mode: MatchExact}, []string{"B"}},
+		{"prefix", "category", []string{"sports"}, true, FilterOptions{Mode: MatchPrefix}, []string{"A", "C"}},
+		{"regex", "category", []string{`^(news|movies)$`}, true, FilterOptions{Mode: MatchRegex}, []string{"B", "D"}},
+		{"case sensitive", "category", []string{"news"}, true, FilterOptions{CaseSensitive: true}, []string{}},
+		{"nested key", "tvg-id", []string{".np"}, true, FilterOptions{}, []string{"A"}},
+	}
+	for _, test := range tests {
+		titles := filterTitles(t, channels, test.key, test.filters, test.retrieve, test.opts)
+		if len(titles) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, titles)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.name, test.expected, titles)
+				break
+			}
+		}
+	}
+
+	if _, err := filterChannels(channels, "category", []string{"("}, true, FilterOptions{Mode: MatchRegex}); err == nil {
+		t.Error("Expected error for invalid regular expression")
+	}
+}
+
+func TestRemoveByCategoryMultipleWords(t *testing.T) {
+	parser := M3uParser{streamsInfo: []Channel{
+		{"title": "A", "category": "Sports News"},
+		{"title": "B", "category": "News"},
+		{"title": "C", "category": "Movies"},
+	}}
+	parser.RemoveByCategory([]string{"news", "sport"})
+	streams := parser.GetStreamsSlice()
+	if len(streams) != 1 || streams[0]["title"] != "C" {
+		t.Errorf("Expected only C to be kept, got %v", streams)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build ignore
+// +build ignore
+
+// gen_schema writes the JSON Schema of the JSON document to schema/channels.v1.schema.json.
+// Run it with go generate in the m3uparser directory.
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	m3uparser "github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+func main() {
+	schema, err := m3uparser.JSONSchema()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("../schema/channels.v1.schema.json", schema, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,308 @@
+package m3uparser
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/epg"
+	log "github.com/sirupsen/logrus"
+)
+
+// guideRef returns the identity of channel used to match it to a guide.
+func guideRef(channel Channel) epg.Ref {
+	tvg, _ := channel["tvg"].(map[string]string)
+	title, _ := channel["title"].(string)
+	return epg.Ref{ID: tvg["id"], Name: tvg["name"], Title: title}
+}
+
+// GuideURLs returns the XMLTV guide URLs of the playlist: the url-tvg and x-tvg-url header
+// attributes, which may hold comma separated lists, followed by the tvg-url of the streams.
+func (p *M3uParser) GuideURLs() []string {
+	seen := make(map[string]bool)
+	var urls []string
+	add := func(value string) {
+		for _, guideURL := range strings.Split(value, ",") {
+			if guideURL = strings.TrimSpace(guideURL); guideURL != "" && !seen[guideURL] {
+				seen[guideURL] = true
+				urls = append(urls, guideURL)
+			}
+		}
+	}
+	add(p.header["url-tvg"])
+	add(p.header["x-tvg-url"])
+	for _, channel := range p.streamsInfo {
+		tvg, _ := channel["tvg"].(map[string]string)
+		add(tvg["url"])
+	}
+	return urls
+}
+
+// LoadGuide loads and merges XMLTV guides, which may be gzip compressed.
+//
+// Parameters:
+//   - sources: URLs or file paths of the guides. Defaults to GuideURLs.
+//
+// It returns an error if a guide can't be loaded.
+func (p *M3uParser) LoadGuide(sources ...string) (*epg.Guide, error) {
+	if len(sources) == 0 {
+		sources = p.GuideURLs()
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no guide url in playlist")
+	}
+	guide := epg.NewGuide()
+	for _, source := range sources {
+		loaded, err := epg.Load(source)
+		if err != nil {
+			return nil, fmt.Errorf("guide %s: %v", source, err)
+		}
+		guide.Merge(loaded)
+	}
+	log.Infof("Loaded %d programmes from %d guides", guide.Len(), len(sources))
+	return guide, nil
+}
+
+// NowNext - The current and next programme of a channel.
+type NowNext struct {
+	Channel Channel
+	// Now is the programme on air, nil if there is none.
+	Now *epg.Programme
+	// Next is the following programme, nil if there is none.
+	Next *epg.Programme
+}
+
+// channelProgrammes returns the programmes of channel ordered by start. A programme without
+// stop time airs until the next one starts.
+func channelProgrammes(guide *epg.Guide, channel Channel) []epg.Programme {
+	programmes := guide.ProgrammesFor(guideRef(channel))
+	for i := range programmes {
+		if programmes[i].Stop.IsZero() && i+1 < len(programmes) {
+			programmes[i].Stop = programmes[i+1].Start
+		}
+	}
+	return programmes
+}
+
+// nowNext returns the programme airing at t and the one after it.
+func nowNext(programmes []epg.Programme, t time.Time) (*epg.Programme, *epg.Programme) {
+	var now, next *epg.Programme
+	for i := range programmes {
+		if programmes[i].Start.After(t) {
+			next = &programmes[i]
+			break
+		}
+		if programmes[i].Airing(t) {
+			now = &programmes[i]
+		}
+	}
+	return now, next
+}
+
+// NowNext returns the current and next programme of every channel at t in channel order.
+// Channels are matched to the guide by tvg-id, tvg-name and title, see epg.Guide.Match.
+func (c Collection) NowNext(guide *epg.Guide, t time.Time) []NowNext {
+	if c.err != nil {
+		return nil
+	}
+	result := make([]NowNext, len(c.channels))
+	for i, channel := range c.channels {
+		result[i].Channel = channel
+		result[i].Now, result[i].Next = nowNext(channelProgrammes(guide, channel), t)
+	}
+	return result
+}
+
+// Airing returns the channels airing a programme between from and to whose title, sub-title or
+// category matches the case-insensitive regular expression pattern. An empty pattern matches
+// every programme and a zero from or to leaves that side of the range open.
+func (c Collection) Airing(guide *epg.Guide, pattern string, from, to time.Time) Collection {
+	if c.err != nil {
+		return c
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return c.with(nil, fmt.Errorf("invalid programme pattern %q: %v", pattern, err))
+	}
+	var airing []Channel
+	for _, channel := range c.channels {
+		for _, programme := range channelProgrammes(guide, channel) {
+			if !programme.Overlaps(from, to) {
+				continue
+			}
+			texts := append([]string{programme.Title, programme.SubTitle}, programme.Categories...)
+			if re.MatchString(strings.Join(texts, "\n")) {
+				airing = append(airing, channel)
+				break
+			}
+		}
+	}
+	return Collection{channels: airing}
+}
+
+// NowNext returns the current and next programme of every stream at t.
+//
+// Parameters:
+//   - guide: Guide loaded with LoadGuide or the epg package.
+//   - t: Time to look up, eg. time.Now().
+func (p *M3uParser) NowNext(guide *epg.Guide, t time.Time) []NowNext {
+	return p.Collection().NowNext(guide, t)
+}
+
+// FilterAiring retrieves the streams airing a programme matching pattern between from and to.
+//
+// Parameters:
+//   - guide: Guide loaded with LoadGuide or the epg package.
+//   - pattern: Case-insensitive regular expression matched against programme title, sub-title and categories.
+//   - from, to: Time range, a zero time leaves that side open.
+//
+// It returns an error if pattern is invalid.
+func (p *M3uParser) FilterAiring(guide *epg.Guide, pattern string, from, to time.Time) error {
+	return p.Apply(p.Collection().Airing(guide, pattern, from, to))
+}
+
+// RenameTvgIDs returns the channels with their tvg-ids renamed. Channels without a tvg-id in
+// renames are shared, renamed channels are copies.
+func (c Collection) RenameTvgIDs(renames map[string]string) Collection {
+	if c.err != nil {
+		return c
+	}
+	renamed := make([]Channel, len(c.channels))
+	for i, channel := range c.channels {
+		renamed[i] = channel
+		tvg, _ := channel["tvg"].(map[string]string)
+		if newID, ok := renames[tvg["id"]]; ok && tvg["id"] != "" {
+			renamedTvg := map[string]string{"id": newID}
+			for key, value := range tvg {
+				if key != "id" {
+					renamedTvg[key] = value
+				}
+			}
+			renamed[i] = copyChannel(channel)
+			renamed[i]["tvg"] = renamedTvg
+		}
+	}
+	return Collection{channels: renamed}
+}
+
+// GuideExportOptions - Options for exporting the guide of the channels.
+type GuideExportOptions struct {
+	// From and To limit the programmes to those airing in the window, a zero time leaves that side open.
+	From, To time.Time
+	// Renames maps original tvg-ids to new ones as given to RenameTvgIDs. Channels are matched to
+	// the guide by their original tvg-id.
+	Renames map[string]string
+}
+
+// ExportGuide returns the part of guide covering the channels: the guide channels matched by the
+// channels and their programmes in the time window. Guide channels are written with the tvg-id of
+// their channel, or their own id for channels without tvg-id, so the guide matches the playlist
+// after renaming tvg-ids. A guide channel is exported once per id, the first channel wins.
+func (c Collection) ExportGuide(guide *epg.Guide, opts GuideExportOptions) (*epg.Guide, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	originals := make(map[string]string, len(opts.Renames))
+	for original, renamed := range opts.Renames {
+		originals[renamed] = original
+	}
+	exported := epg.NewGuide()
+	exported.SetAttrs(guide.Attrs())
+	written := make(map[string]bool)
+	for _, channel := range c.channels {
+		ref := guideRef(channel)
+		id := ref.ID
+		if original, ok := originals[ref.ID]; ok {
+			ref.ID = original
+		}
+		guideID, method := guide.Match(ref)
+		if method == epg.NoMatch {
+			continue
+		}
+		if id == "" {
+			id = guideID
+		}
+		if written[id] {
+			continue
+		}
+		written[id] = true
+		guideChannel, ok := guide.Channel(guideID)
+		if !ok {
+			guideChannel = epg.Channel{DisplayNames: []string{ref.Title}}
+		}
+		guideChannel.ID = id
+		exported.AddChannel(guideChannel)
+		programmes := guide.Programmes(guideID)
+		for i, programme := range programmes {
+			// a programme without stop time airs until the next one starts
+			window := programme
+			if window.Stop.IsZero() && i+1 < len(programmes) {
+				window.Stop = programmes[i+1].Start
+			}
+			if window.Overlaps(opts.From, opts.To) {
+				programme.Channel = id
+				exported.AddProgramme(programme)
+			}
+		}
+	}
+	return exported, nil
+}
+
+// WriteGuide writes the part of guide covering the channels as XMLTV, see ExportGuide.
+func (c Collection) WriteGuide(w io.Writer, guide *epg.Guide, opts GuideExportOptions) error {
+	exported, err := c.ExportGuide(guide, opts)
+	if err != nil {
+		return err
+	}
+	return exported.Write(w)
+}
+
+// RenameTvgIDs renames the tvg-ids of the streams.
+//
+// Parameters:
+//   - renames: Map of original tvg-ids to new ones. Pass it to GuideToFile as GuideExportOptions.Renames
+//     so the guide uses the new ids too.
+func (p *M3uParser) RenameTvgIDs(renames map[string]string) {
+	p.Apply(p.Collection().RenameTvgIDs(renames))
+}
+
+// GuideToFile saves the part of guide covering the current streams information as XMLTV file.
+// It keeps only the guide channels of the streams and the programmes in the time window.
+// The file is gzip compressed if fileName ends with ".gz".
+//
+// Parameters:
+//   - guide: Guide loaded with LoadGuide or the epg package.
+//   - fileName: Name of the file, eg. "guide.xml" or "guide.xml.gz".
+//   - opts: From/To time window and Renames of tvg-ids, see GuideExportOptions.
+func (p *M3uParser) GuideToFile(guide *epg.Guide, fileName string, opts GuideExportOptions) error {
+	exported, err := p.Collection().ExportGuide(guide, opts)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	log.Infof("Saving guide of %d channels to file: %s", len(exported.Channels()), fileName)
+	if err := writeGuide(file, exported, strings.HasSuffix(strings.ToLower(fileName), ".gz")); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeGuide writes guide to w, gzip compressed if compress is true.
+func writeGuide(w io.Writer, guide *epg.Guide, compress bool) error {
+	if !compress {
+		return guide.Write(w)
+	}
+	writer := gzip.NewWriter(w)
+	if err := guide.Write(writer); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,142 @@
+package m3uparser
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/epg"
+)
+
+const testGuide = `<?xml version="1.0" encoding="UTF-8"?>
+<tv>
+  <channel id="KantipurTV.np"><display-name>Kantipur TV</display-name></channel>
+  <channel id="Image.np"><display-name>Image Channel</display-name></channel>
+  <programme start="20240101100000 +0000" stop="20240101110000 +0000" channel="KantipurTV.np"><title>Morning News</title></programme>
+  <programme start="20240101110000 +0000" stop="20240101120000 +0000" channel="KantipurTV.np"><title>Cooking</title><category>Food</category></programme>
+  <programme start="20240101100000 +0000" channel="Image.np"><title>Music</title></programme>
+  <programme start="20240101113000 +0000" channel="Image.np"><title>Evening News</title></programme>
+</tv>`
+
+func newGuideParser(t *testing.T) (*M3uParser, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(testGuide))
+		writer.Close()
+	}))
+	parser := &M3uParser{}
+	parser.ParseM3u(`#EXTM3U url-tvg="`+server.URL+`/guide.xml.gz" catchup="shift"
+#EXTINF:-1 tvg-id="KantipurTV.np",Kantipur TV (720p)
+http://example.com/k.m3u8
+#EXTINF:-1 tvg-name="Image Channel",Image
+http://example.com/i.m3u8
+#EXTINF:-1,Unknown
+http://example.com/u.m3u8`, false, false)
+	return parser, server
+}
+
+func TestParseHeader(t *testing.T) {
+	parser, server := newGuideParser(t)
+	defer server.Close()
+	expected := map[string]string{"url-tvg": server.URL + "/guide.xml.gz", "catchup": "shift"}
+	if !reflect.DeepEqual(parser.Header(), expected) {
+		t.Errorf("Expected header %v, got %v", expected, parser.Header())
+	}
+	if urls := parser.GuideURLs(); len(urls) != 1 || urls[0] != expected["url-tvg"] {
+		t.Errorf("Unexpected guide urls %v", urls)
+	}
+}
+
+func TestNowNext(t *testing.T) {
+	parser, server := newGuideParser(t)
+	defer server.Close()
+	guide, err := parser.LoadGuide()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	result := parser.NowNext(guide, at)
+	if len(result) != 3 {
+		t.Fatalf("Expected now/next of 3 channels, got %d", len(result))
+	}
+	if result[0].Now == nil || result[0].Now.Title != "Morning News" || result[0].Next == nil || result[0].Next.Title != "Cooking" {
+		t.Errorf("Unexpected now/next %+v %+v", result[0].Now, result[0].Next)
+	}
+	if result[1].Now == nil || result[1].Now.Title != "Music" || !result[1].Now.Stop.Equal(at.Add(time.Hour)) {
+		t.Errorf("Expected Music until the next programme, got %+v", result[1].Now)
+	}
+	if result[2].Now != nil || result[2].Next != nil {
+		t.Errorf("Expected no programmes for unknown channel, got %+v", result[2])
+	}
+}
+
+func TestFilterAiring(t *testing.T) {
+	parser, server := newGuideParser(t)
+	defer server.Close()
+	guide, err := parser.LoadGuide()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	if err := parser.FilterAiring(guide, "news", from, from.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if titles := collectionTitles(parser.GetStreamsSlice()); !reflect.DeepEqual(titles, []string{"Image"}) {
+		t.Errorf("Expected only Image airing news, got %v", titles)
+	}
+	parser.Undo()
+	if err := parser.FilterAiring(guide, "food", time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if titles := collectionTitles(parser.GetStreamsSlice()); !reflect.DeepEqual(titles, []string{"Kantipur TV (720p)"}) {
+		t.Errorf("Expected only Kantipur TV airing food, got %v", titles)
+	}
+	if err := parser.FilterAiring(guide, "(", time.Time{}, time.Time{}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestExportGuide(t *testing.T) {
+	parser, server := newGuideParser(t)
+	defer server.Close()
+	guide, err := parser.LoadGuide()
+	if err != nil {
+		t.Fatal(err)
+	}
+	renames := map[string]string{"KantipurTV.np": "ktv"}
+	parser.RenameTvgIDs(renames)
+	if tvg := parser.GetStreamsSlice()[0]["tvg"].(map[string]string); tvg["id"] != "ktv" {
+		t.Errorf("Expected renamed tvg-id, got %v", tvg)
+	}
+	if tvg := parser.streamsInfoBackup[0]["tvg"].(map[string]string); tvg["id"] != "KantipurTV.np" {
+		t.Errorf("Expected original streams to be unchanged, got %v", tvg)
+	}
+
+	from := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	fileName := "guide_test.xml.gz"
+	if err := parser.GuideToFile(guide, fileName, GuideExportOptions{From: from, Renames: renames}); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fileName)
+	exported, err := epg.Load(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, channel := range exported.Channels() {
+		ids = append(ids, channel.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"ktv", "Image.np"}) {
+		t.Errorf("Expected channels ktv and Image.np, got %v", ids)
+	}
+	if programmes := exported.Programmes("ktv"); len(programmes) != 1 || programmes[0].Title != "Cooking" {
+		t.Errorf("Expected only Cooking in the window, got %+v", programmes)
+	}
+	if programmes := exported.Programmes("Image.np"); len(programmes) != 2 {
+		t.Errorf("Expected Music without stop and Evening News, got %+v", programmes)
+	}
+}
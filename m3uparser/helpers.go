@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -47,3 +49,28 @@ func Get(URL string, userAgent string, timeout time.Duration) (*http.Response, e
 	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
 	return resp, err
 }
+
+// copyChannel returns a shallow copy of channel.
+func copyChannel(channel Channel) Channel {
+	copied := make(Channel, len(channel))
+	for key, value := range channel {
+		copied[key] = value
+	}
+	return copied
+}
+
+// toFloat converts numeric values and numeric strings to float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
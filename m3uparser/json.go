@@ -0,0 +1,364 @@
+package m3uparser
+
+//go:generate go run gen_schema.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// nestedKeys are the channel keys holding a map[string]string.
+var nestedKeys = map[string]bool{"tvg": true, "country": true, "attributes": true}
+
+// documentKeys are the channel keys with a field in JSONChannel, other keys are kept in Extra.
+var documentKeys = map[string]bool{
+	"title": true, "logo": true, "category": true, "language": true, "url": true, "tvg": true, "country": true,
+	"status": true, "latency": true, "duration": true, "source": true, "alternatives": true, "attributes": true,
+}
+
+// ReadJSON reads channels from JSON.
+// It accepts the versioned JSONDocument, which is validated with ValidateJSON, the list of channels
+// written by older versions, including null values, and iptv-org style lists of channels ({"name",
+// "logo", "url", "categories", "countries", "languages", "tvg"}) or streams ({"channel", "title",
+// "url", "quality", "referrer", "user_agent"}).
+// Null and empty values are kept as empty strings with enforceSchema and dropped otherwise,
+// nested values like country.name are always kept.
+func ReadJSON(r io.Reader, enforceSchema bool) ([]Channel, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "{") {
+		if err := ValidateJSON(strings.NewReader(string(content))); err != nil {
+			return nil, err
+		}
+		var document JSONDocument
+		if err := json.Unmarshal(content, &document); err != nil {
+			return nil, err
+		}
+		channels := make([]Channel, len(document.Channels))
+		for i, jsonChannel := range document.Channels {
+			channels[i] = jsonChannel.channel(enforceSchema)
+			if streamURL, _ := channels[i]["url"].(string); streamURL == "" {
+				return nil, fmt.Errorf("channels[%d].url: missing url", i)
+			}
+		}
+		return channels, nil
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, fmt.Errorf("invalid json channel list: %v", err)
+	}
+	channels := make([]Channel, 0, len(items))
+	for i, item := range items {
+		var channel Channel
+		switch {
+		case item["title"] == nil && item["name"] != nil:
+			channel = channelFromIptvOrgChannel(item)
+		case item["channel"] != nil && item["tvg"] == nil:
+			channel = channelFromIptvOrgStream(item)
+		default:
+			channel = channelFromJSON(item, enforceSchema)
+		}
+		if streamURL, _ := channel["url"].(string); streamURL == "" {
+			return nil, fmt.Errorf("json channel %d: missing url", i)
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// nullString returns nil for an empty or missing value.
+func nullString(value interface{}) *string {
+	str, ok := value.(string)
+	if !ok || str == "" {
+		return nil
+	}
+	return &str
+}
+
+// newJSONChannel converts a channel to the document representation.
+func newJSONChannel(channel Channel) JSONChannel {
+	tvg, _ := channel["tvg"].(map[string]string)
+	country, _ := channel["country"].(map[string]string)
+	jsonChannel := JSONChannel{
+		Title:    nullString(channel["title"]),
+		Logo:     nullString(channel["logo"]),
+		Category: nullString(channel["category"]),
+		Language: nullString(channel["language"]),
+		URL:      nullString(channel["url"]),
+		Tvg:      JSONTvg{ID: nullString(tvg["id"]), Name: nullString(tvg["name"]), URL: nullString(tvg["url"])},
+		Country:  JSONCountry{Code: nullString(country["code"]), Name: nullString(country["name"])},
+		Status:   nullString(channel["status"]),
+		Source:   nullString(channel["source"]),
+	}
+	if latency, ok := toFloat(channel["latency"]); ok {
+		milliseconds := int64(latency)
+		jsonChannel.Latency = &milliseconds
+	}
+	if seconds := durationSeconds(channel); seconds > 0 {
+		jsonChannel.Duration = &seconds
+	}
+	if alternatives, ok := channel["alternatives"].([]string); ok && len(alternatives) > 0 {
+		jsonChannel.Alternatives = alternatives
+	}
+	if attributes, ok := channel["attributes"].(map[string]string); ok && len(attributes) > 0 {
+		jsonChannel.Attributes = attributes
+	}
+	for key, value := range channel {
+		if !documentKeys[key] && value != nil {
+			if jsonChannel.Extra == nil {
+				jsonChannel.Extra = make(map[string]interface{})
+			}
+			jsonChannel.Extra[key] = value
+		}
+	}
+	return jsonChannel
+}
+
+// channel converts the document representation to a channel like the parser creates it.
+func (c JSONChannel) channel(enforceSchema bool) Channel {
+	channel := make(Channel)
+	setString := func(key string, value *string) {
+		if value != nil {
+			channel[key] = *value
+		} else if enforceSchema {
+			channel[key] = ""
+		}
+	}
+	setString("title", c.Title)
+	setString("logo", c.Logo)
+	setString("category", c.Category)
+	setString("language", c.Language)
+	setString("url", c.URL)
+	tvg := make(map[string]string)
+	for key, value := range map[string]*string{"id": c.Tvg.ID, "name": c.Tvg.Name, "url": c.Tvg.URL} {
+		if value != nil {
+			tvg[key] = *value
+		} else if enforceSchema {
+			tvg[key] = ""
+		}
+	}
+	if len(tvg) > 0 {
+		channel["tvg"] = tvg
+	}
+	if c.Country.Code != nil || c.Country.Name != nil || enforceSchema {
+		// the parser always sets both keys of country
+		country := map[string]string{"code": "", "name": ""}
+		if c.Country.Code != nil {
+			country["code"] = *c.Country.Code
+		}
+		if c.Country.Name != nil {
+			country["name"] = *c.Country.Name
+		}
+		channel["country"] = country
+	}
+	if c.Status != nil {
+		channel["status"] = *c.Status
+	}
+	if c.Latency != nil {
+		channel["latency"] = *c.Latency
+	}
+	if c.Duration != nil {
+		setDuration(channel, *c.Duration)
+	}
+	if c.Source != nil {
+		channel["source"] = *c.Source
+	}
+	if len(c.Alternatives) > 0 {
+		channel["alternatives"] = c.Alternatives
+	}
+	if len(c.Attributes) > 0 {
+		channel["attributes"] = c.Attributes
+	}
+	for key, value := range c.Extra {
+		if !documentKeys[key] {
+			channel[key] = value
+		}
+	}
+	return channel
+}
+
+// WriteJSON writes the channels as indented JSONDocument of version JSONSchemaVersion.
+func (c Collection) WriteJSON(w io.Writer) error {
+	if c.err != nil {
+		return c.err
+	}
+	document := JSONDocument{Version: JSONSchemaVersion, Channels: make([]JSONChannel, len(c.channels))}
+	for i, channel := range c.channels {
+		document.Channels[i] = newJSONChannel(channel)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(document)
+}
+
+// jsonString returns value as string, empty for null and other types.
+func jsonString(value interface{}) string {
+	str, _ := value.(string)
+	return strings.TrimSpace(str)
+}
+
+// channelFromJSON converts an item of the JSON list written by older versions to a channel.
+func channelFromJSON(item map[string]interface{}, enforceSchema bool) Channel {
+	channel := make(Channel, len(item))
+	for key, value := range item {
+		switch v := value.(type) {
+		case nil:
+			if enforceSchema {
+				channel[key] = ""
+			}
+		case string:
+			if v != "" || enforceSchema {
+				channel[key] = v
+			}
+		case map[string]interface{}:
+			nested := make(map[string]string, len(v))
+			for nestedKey, nestedValue := range v {
+				// nested keys are kept like the parser does, a null value is an empty string
+				nested[nestedKey] = jsonString(nestedValue)
+			}
+			if len(nested) > 0 || enforceSchema {
+				channel[key] = nested
+			}
+		case []interface{}:
+			list := make([]string, 0, len(v))
+			for _, element := range v {
+				if str := jsonString(element); str != "" {
+					list = append(list, str)
+				}
+			}
+			if len(list) > 0 {
+				channel[key] = list
+			}
+		case float64:
+			if key == "latency" {
+				channel[key] = int64(v)
+			} else {
+				channel[key] = v
+			}
+		default:
+			channel[key] = v
+		}
+	}
+	for key := range nestedKeys {
+		if _, ok := channel[key].(string); ok {
+			// a null nested value is kept as an empty map
+			channel[key] = map[string]string{}
+		}
+	}
+	return channel
+}
+
+// iptvOrgNames returns the "name" of every object in a list of iptv-org categories, countries or languages.
+func iptvOrgNames(value interface{}, key string) []string {
+	list, _ := value.([]interface{})
+	var names []string
+	for _, element := range list {
+		object, _ := element.(map[string]interface{})
+		if name := jsonString(object[key]); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// channelFromIptvOrgChannel converts an iptv-org channel to a channel. Multiple categories and languages
+// are joined with ";" like in iptv-org playlists and the first country is used.
+func channelFromIptvOrgChannel(item map[string]interface{}) Channel {
+	channel := Channel{"url": jsonString(item["url"])}
+	if title := jsonString(item["name"]); title != "" {
+		channel["title"] = title
+	}
+	if logo := jsonString(item["logo"]); logo != "" {
+		channel["logo"] = logo
+	}
+	if categories := iptvOrgNames(item["categories"], "name"); len(categories) > 0 {
+		channel["category"] = strings.Join(categories, ";")
+	}
+	if languages := iptvOrgNames(item["languages"], "name"); len(languages) > 0 {
+		channel["language"] = strings.Join(languages, ";")
+	}
+	if codes := iptvOrgNames(item["countries"], "code"); len(codes) > 0 {
+		names := iptvOrgNames(item["countries"], "name")
+		country := map[string]string{"code": strings.ToUpper(codes[0])}
+		if len(names) > 0 {
+			country["name"] = names[0]
+		}
+		channel["country"] = country
+	}
+	if object, ok := item["tvg"].(map[string]interface{}); ok {
+		tvg := make(map[string]string)
+		for key, value := range object {
+			if str := jsonString(value); str != "" {
+				tvg[key] = str
+			}
+		}
+		if len(tvg) > 0 {
+			channel["tvg"] = tvg
+		}
+	}
+	return channel
+}
+
+// channelFromIptvOrgStream converts an iptv-org stream to a channel. The quality is appended to
+// the title and referrer and user agent become http-referrer and http-user-agent attributes
+// like in iptv-org playlists.
+func channelFromIptvOrgStream(item map[string]interface{}) Channel {
+	id := jsonString(item["channel"])
+	channel := Channel{"url": jsonString(item["url"]), "tvg": map[string]string{"id": id}}
+	title := jsonString(item["title"])
+	if title == "" {
+		title = id
+	}
+	if quality := jsonString(item["quality"]); quality != "" {
+		title += fmt.Sprintf(" (%s)", quality)
+	}
+	channel["title"] = title
+	attributes := make(map[string]string)
+	if referrer := jsonString(item["referrer"]); referrer != "" {
+		attributes["http-referrer"] = referrer
+	}
+	if userAgent := jsonString(item["user_agent"]); userAgent != "" {
+		attributes["http-user-agent"] = userAgent
+	}
+	if len(attributes) > 0 {
+		channel["attributes"] = attributes
+	}
+	return channel
+}
+
+// ParseJSON parses JSON content of local file/URL or raw JSON content.
+// It reads the JSON saved by ToFile, also by older versions, or iptv-org style channel lists, see ReadJSON.
+// Any previously parsed streams information is replaced.
+//
+// Parameters:
+//   - source: URL, file path or raw JSON content.
+//   - checkLive: Boolean flag to check if stream URLs are accessible and working
+//   - enforceSchema: If true, keeps null and empty values as empty strings; if false, removes them
+func (p *M3uParser) ParseJSON(source string, checkLive bool, enforceSchema bool) error {
+	p.setup(checkLive, enforceSchema)
+	content, err := p.readSource(source)
+	if err != nil {
+		return err
+	}
+	streams, err := ReadJSON(strings.NewReader(content), enforceSchema)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		log.Infoln("No content to parse!!!")
+	}
+	if p.CheckLive {
+		p.checkChannels(streams)
+	}
+	p.load(streams)
+	return nil
+}
@@ -0,0 +1,170 @@
+package m3uparser
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, enforceSchema := range []bool{false, true} {
+		parser := M3uParser{}
+		parser.ParseM3u(`#EXTM3U
+#EXTINF:120 tvg-id="a.np" tvg-country="NP" tvg-chno="7" group-title="News",Channel A
+http://example.com/a.m3u8
+#EXTINF:-1,Channel B
+http://example.com/b.m3u8`, false, enforceSchema)
+
+		fileName := "roundtrip_test.json"
+		parser.ToFile(fileName)
+		content, err := ioutil.ReadFile(fileName)
+		os.Remove(fileName)
+		if err != nil {
+			t.Fatalf("File is not saved: %v", err)
+		}
+		if err := ValidateJSON(strings.NewReader(string(content))); err != nil {
+			t.Errorf("enforceSchema %v: saved json is invalid: %v", enforceSchema, err)
+		}
+
+		decoded := M3uParser{}
+		if err := decoded.ParseJSON(string(content), false, enforceSchema); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded.GetStreamsSlice(), parser.GetStreamsSlice()) {
+			t.Errorf("enforceSchema %v: expected %v, got %v", enforceSchema, parser.GetStreamsSlice(), decoded.GetStreamsSlice())
+		}
+
+		detected := M3uParser{}
+		detected.ParseM3u(string(content), false, enforceSchema)
+		if !reflect.DeepEqual(detected.GetStreamsSlice(), parser.GetStreamsSlice()) {
+			t.Errorf("enforceSchema %v: expected ParseM3u to detect json, got %v", enforceSchema, detected.GetStreamsSlice())
+		}
+	}
+}
+
+func TestReadJSONIptvOrg(t *testing.T) {
+	channels, err := ReadJSON(strings.NewReader(`[
+		{"name": "Kantipur TV", "logo": "http://example.com/k.png", "url": "http://example.com/k.m3u8",
+		 "categories": [{"name": "General", "slug": "general"}, {"name": "News", "slug": "news"}],
+		 "countries": [{"name": "Nepal", "code": "np"}], "languages": [{"name": "Nepali", "code": "nep"}],
+		 "tvg": {"id": "KantipurTV.np", "name": null, "url": ""}},
+		{"channel": "ABC.us", "title": "ABC", "url": "http://example.com/abc.m3u8", "feed": null,
+		 "referrer": "http://example.com/", "user_agent": null, "quality": "720p"}
+	]`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Channel{
+		{
+			"title": "Kantipur TV", "logo": "http://example.com/k.png", "url": "http://example.com/k.m3u8",
+			"category": "General;News", "language": "Nepali",
+			"country": map[string]string{"code": "NP", "name": "Nepal"},
+			"tvg":     map[string]string{"id": "KantipurTV.np"},
+		},
+		{
+			"title": "ABC (720p)", "url": "http://example.com/abc.m3u8",
+			"tvg":        map[string]string{"id": "ABC.us"},
+			"attributes": map[string]string{"http-referrer": "http://example.com/"},
+		},
+	}
+	if !reflect.DeepEqual(channels, expected) {
+		t.Errorf("Expected %v, got %v", expected, channels)
+	}
+}
+
+func TestReadJSONErrors(t *testing.T) {
+	if _, err := ReadJSON(strings.NewReader(`[{"title": "A"}]`), false); err == nil || !strings.Contains(err.Error(), "channel 0") {
+		t.Errorf("Expected missing url error for channel 0, got %v", err)
+	}
+	if _, err := ReadJSON(strings.NewReader(`[{"title": "A",`), false); err == nil {
+		t.Error("Expected error for invalid json")
+	}
+}
+
+func TestJSONDocumentShape(t *testing.T) {
+	collection := NewCollection([]Channel{
+		{"url": "http://example.com/a?b=1&c=2", "title": `He said ": ""`, "latency": int64(42), "uptime": 0.5},
+	})
+	var sb strings.Builder
+	if err := collection.WriteJSON(&sb); err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(sb.String()), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document["version"] != float64(JSONSchemaVersion) {
+		t.Errorf("Expected version %d, got %v", JSONSchemaVersion, document["version"])
+	}
+	channel := document["channels"].([]interface{})[0].(map[string]interface{})
+	if channel["title"] != `He said ": ""` || channel["logo"] != nil || channel["url"] != "http://example.com/a?b=1&c=2" {
+		t.Errorf("Unexpected channel %v", channel)
+	}
+	if tvg, ok := channel["tvg"].(map[string]interface{}); !ok || len(tvg) != 3 || tvg["id"] != nil {
+		t.Errorf("Expected tvg with null keys, got %v", channel["tvg"])
+	}
+	if !reflect.DeepEqual(channel["extra"], map[string]interface{}{"uptime": 0.5}) {
+		t.Errorf("Expected extra keys, got %v", channel["extra"])
+	}
+
+	channels, err := ReadJSON(strings.NewReader(sb.String()), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(channels, collection.Channels()) {
+		t.Errorf("Expected %v, got %v", collection.Channels(), channels)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{`[]`, "document: expected object, got array"},
+		{`{"version": 2, "channels": []}`, "version: expected 1, got 2"},
+		{`{"version": 1}`, `document: missing key "channels"`},
+		{`{"version": 1, "channels": null}`, "channels: expected array, got null"},
+		{`{"version": 1, "channels": [], "other": 1}`, `document: unknown key "other"`},
+		{`{"version": 1, "channels": [{"url": "http://a"}]}`, `channels[0]: missing key "title"`},
+		{`{"version": 1, "channels": [{"title": null, "logo": null, "category": null, "language": null,
+			"url": "http://a", "tvg": {"id": 7, "name": null, "url": null}}]}`, "channels[0].tvg.id: expected string or null, got integer"},
+		{`{"version": 1, "channels": [{"title": null, "logo": null, "category": null, "language": null,
+			"url": "http://a", "tvg": {"id": null, "name": null, "url": null}, "country": {"code": null, "name": null},
+			"status": null, "latency": 1.5, "duration": null, "source": null, "alternatives": null,
+			"attributes": null, "extra": null}]}`, "channels[0].latency: expected integer or null, got number"},
+		{`{"version": 1, "channels": [{"title": null, "logo": null, "category": null, "language": null,
+			"url": "http://a", "tvg": {"id": null, "name": null, "url": null}, "country": {"code": null, "name": null},
+			"status": null, "latency": 12, "duration": 3, "source": null, "alternatives": ["http://b"],
+			"attributes": {"tvg-chno": "7"}, "extra": {"uptime": 0.5}}]}`, ""},
+	}
+	for _, test := range tests {
+		err := ValidateJSON(strings.NewReader(test.content))
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", test.content, err)
+		}
+		if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.content, test.err, err)
+		}
+	}
+	if _, err := ReadJSON(strings.NewReader(`{"version": 2, "channels": []}`), false); err == nil {
+		t.Error("Expected ReadJSON to reject unsupported version")
+	}
+}
+
+func TestJSONSchemaFile(t *testing.T) {
+	content, err := ioutil.ReadFile("../schema/channels.v1.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(schema) {
+		t.Error("schema/channels.v1.schema.json is outdated, run go generate ./m3uparser")
+	}
+}
@@ -1,14 +1,13 @@
 package m3uparser
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
-	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -26,7 +25,10 @@ type Channel map[string]interface{}
 type M3uParser struct {
 	streamsInfo       []Channel
 	streamsInfoBackup []Channel
+	undoHistory       [][]Channel
+	redoHistory       [][]Channel
 	enforceSchema     bool
+	header            map[string]string
 	lines             []string
 	Timeout           int
 	UserAgent         string
@@ -34,11 +36,23 @@ type M3uParser struct {
 	content           string
 	regexes           map[string]*regexp.Regexp
 	mutex             sync.Mutex
+	wg                sync.WaitGroup
+	bar               *pb.ProgressBar
 }
 
 var countryClient *country_mapper.CountryInfoClient
-var wg sync.WaitGroup
-var bar *pb.ProgressBar
+
+// knownAttributes are the #EXTINF attributes parsed into their own keys.
+// Other attributes are kept under the "attributes" key.
+var knownAttributes = map[string]bool{
+	"tvg-name":     true,
+	"tvg-id":       true,
+	"tvg-url":      true,
+	"tvg-logo":     true,
+	"tvg-country":  true,
+	"tvg-language": true,
+	"group-title":  true,
+}
 
 func init() {
 	client, err := country_mapper.Load()
@@ -52,7 +66,7 @@ func init() {
 	log.SetFormatter(&log.TextFormatter{TimestampFormat: "2006-01-02 15:04:05", FullTimestamp: true})
 	// Only log the warning severity or above.
 	log.SetLevel(log.InfoLevel)
-	log.Infoln("Parser started")
+	log.Debugln("Parser started")
 }
 
 func errorLogger(err error) {
@@ -66,40 +80,59 @@ func (p *M3uParser) isEmpty() bool {
 	return len(p.streamsInfo) == 0
 }
 
-func (p *M3uParser) isLive(url string, channel Channel) {
-	defer wg.Done()
-	_, err := Get(url, p.UserAgent, time.Duration(p.Timeout)*time.Second)
+func (p *M3uParser) isLive(channel Channel) {
+	defer p.wg.Done()
+	start := time.Now()
+	resp, err := Get(channel["url"].(string), p.UserAgent, time.Duration(p.Timeout)*time.Second)
+	latency := time.Since(start).Milliseconds()
+	p.mutex.Lock()
 	if err != nil {
 		channel["status"] = "BAD"
+		delete(channel, "latency")
 	} else {
+		resp.Body.Close()
 		channel["status"] = "GOOD"
+		channel["latency"] = latency
 	}
-	bar.Increment()
+	p.mutex.Unlock()
+	p.bar.Increment()
 }
 
-// ParseM3u parses the content of local file/URL or raw M3U content.
-// It downloads the file from the given URL, reads from a local file path, or parses raw M3U content directly.
-// The function parses line by line to extract stream information into a structured format.
-//
-// Parameters:
-//   - source: Can be one of the following:
-//   - URL: A valid HTTP/HTTPS URL pointing to an M3U file
-//   - File path: Local file path to an M3U file (e.g., "/path/to/file.m3u")
-//   - Raw content: M3U content string
-//   - checkLive: Boolean flag to check if stream URLs are accessible and working
-//   - enforceSchema: If true, keeps all fields even with empty values; if false, removes keys with empty string values
-func (p *M3uParser) ParseM3u(source string, checkLive bool, enforceSchema bool) {
+// checkChannels checks the liveness of the given channels concurrently and sets their status.
+func (p *M3uParser) checkChannels(channels []Channel) {
+	if len(channels) == 0 {
+		return
+	}
+	p.bar = pb.StartNew(len(channels))
+	p.wg.Add(len(channels))
+	for _, channel := range channels {
+		if url, _ := channel["url"].(string); !isValidURL(url) {
+			// local files are considered live
+			channel["status"] = "GOOD"
+			p.bar.Increment()
+			p.wg.Done()
+			continue
+		}
+		go p.isLive(channel)
+	}
+	p.wg.Wait()
+	p.bar.Finish()
+}
+
+func (p *M3uParser) setup(checkLive bool, enforceSchema bool) {
 	p.enforceSchema = enforceSchema
+	p.header = make(map[string]string)
 	p.regexes = make(map[string]*regexp.Regexp)
 	p.regexes["file"] = compileRegex(`(?m)^[a-zA-Z]:\\((?:.*?\\)*).*.[\d\w]{3,5}$|^(/[^/]*)+/?.[\d\w]{3,5}$`)
 	p.regexes["tvgName"] = compileRegex("tvg-name=\"(.*?)\"")
 	p.regexes["tvgID"] = compileRegex("tvg-id=\"(.*?)\"")
 	p.regexes["logo"] = compileRegex("tvg-logo=\"(.*?)\"")
 	p.regexes["category"] = compileRegex("group-title=\"(.*?)\"")
-	p.regexes["title"] = compileRegex(`[,](.*?)$`)
+	p.regexes["duration"] = compileRegex(`^#EXTINF:\s*(-?[\d.]+)`)
 	p.regexes["countryCode"] = compileRegex("tvg-country=\"(.*?)\"")
 	p.regexes["language"] = compileRegex("tvg-language=\"(.*?)\"")
 	p.regexes["tvgURL"] = compileRegex("tvg-url=\"(.*?)\"")
+	p.regexes["attributes"] = compileRegex(`([\w-]+)="(.*?)"`)
 
 	if p.Timeout == 0 {
 		p.Timeout = 5
@@ -108,220 +141,423 @@ func (p *M3uParser) ParseM3u(source string, checkLive bool, enforceSchema bool)
 		p.UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.198 Safari/537.36"
 	}
 	p.CheckLive = checkLive
+}
 
-	// Check if source starts with "#EXTM3U", is empty, or contains newlines - if so, treat as raw content
+// isRawContent reports whether source is playlist content rather than a URL or file path.
+func isRawContent(source string) bool {
 	trimmedSource := strings.TrimSpace(source)
-	if strings.HasPrefix(trimmedSource, "#EXTM3U") || trimmedSource == "" || strings.Contains(source, "\n") {
+	return strings.HasPrefix(trimmedSource, "#EXTM3U") || strings.HasPrefix(trimmedSource, "[") ||
+		strings.HasPrefix(trimmedSource, "{") || strings.HasPrefix(trimmedSource, "<") || trimmedSource == "" || strings.Contains(source, "\n")
+}
+
+// readSource returns the content of source which can be raw content, URL or file path. URLs are
+// requested with the UserAgent and must be read within the Timeout.
+func (p *M3uParser) readSource(source string) (string, error) {
+	if isRawContent(source) {
 		log.Infoln("Started parsing m3u from raw content...")
-		p.content = source
-	} else if isValidURL(source) {
+		return source, nil
+	}
+	if isValidURL(source) {
 		log.Infoln("Started parsing m3u URL...")
-		resp, err := http.Get(source)
-		errorLogger(err)
+		// the client timeout covers reading the body, unlike the request context of Get
+		client := &http.Client{Timeout: time.Duration(p.Timeout) * time.Second}
+		request, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return "", err
+		}
+		request.Header.Set("User-Agent", p.UserAgent)
+		resp, err := client.Do(request)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
 		body, err := ioutil.ReadAll(resp.Body)
-		errorLogger(err)
-		p.content = string(body)
-	} else {
-		log.Infoln("Started parsing m3u file...")
-		body, err := ioutil.ReadFile(source)
-		errorLogger(err)
-		p.content = string(body)
-	}
-	if p.content != "" {
-		for _, line := range strings.Split(p.content, "\n") {
-			if strings.TrimSpace(line) != "" {
-				p.lines = append(p.lines, strings.TrimSpace(line))
-			}
+		if err != nil {
+			return "", err
 		}
+		return string(body), nil
 	}
-	if len(p.lines) > 0 {
-		p.parseLines()
-	} else {
-		log.Infoln("No content to parse!!!")
+	log.Infoln("Started parsing m3u file...")
+	body, err := ioutil.ReadFile(source)
+	if err != nil {
+		return "", err
 	}
-	p.streamsInfoBackup = p.streamsInfo
+	return string(body), nil
 }
 
-func (p *M3uParser) parseLines() {
-	re := compileRegex("#EXTINF")
-	var count int
-	for lineNumber := range p.lines {
-		if re.Match([]byte(p.lines[lineNumber])) {
-			count++
+// ParseM3u parses the content of local file/URL or raw M3U content.
+// It downloads the file from the given URL, reads from a local file path, or parses raw M3U content directly.
+// The function parses line by line to extract stream information into a structured format.
+// PLS, XSPF and ASX playlists are also parsed, the format is detected from the content.
+// Any previously parsed streams information is replaced.
+//
+// Parameters:
+//   - source: Can be one of the following:
+//   - URL: A valid HTTP/HTTPS URL pointing to an M3U file
+//   - File path: Local file path to an M3U file (e.g., "/path/to/file.m3u")
+//   - Raw content: M3U content string
+//   - checkLive: Boolean flag to check if stream URLs are accessible and working
+//   - enforceSchema: If true, keeps all fields even with empty values; if false, removes keys with empty string values
+func (p *M3uParser) ParseM3u(source string, checkLive bool, enforceSchema bool) {
+	p.setup(checkLive, enforceSchema)
+	content, err := p.readSource(source)
+	errorLogger(err)
+	streams, err := p.decodeContent(content)
+	errorLogger(err)
+	if p.CheckLive {
+		p.checkChannels(streams)
+	}
+	p.load(streams)
+}
+
+// load replaces the streams information and clears the operations history.
+func (p *M3uParser) load(streams []Channel) {
+	p.streamsInfo = streams
+	p.streamsInfoBackup = streams
+	p.undoHistory = nil
+	p.redoHistory = nil
+}
+
+// Load loads channels from another source, eg. the xtream package, as streams information.
+// Any previously parsed streams information is replaced.
+//
+// Parameters:
+//   - channels: Channels to load, they are checked in place with checkLive.
+//   - checkLive: Boolean flag to check if stream URLs are accessible and working
+func (p *M3uParser) Load(channels []Channel, checkLive bool) {
+	p.setup(checkLive, false)
+	if p.CheckLive {
+		p.checkChannels(channels)
+	}
+	p.load(channels)
+}
+
+// SourceError is the error reported for a source that could not be loaded by ParseSources.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ParseSources parses multiple sources into one merged streams information slice.
+// Sources are fetched concurrently and merged in the given order. Every channel is tagged
+// with its origin under the "source" key: the URL or file path, or "raw:<index>" for raw content.
+// A source that fails to load is reported and skipped without aborting the others.
+// Any previously parsed streams information is replaced.
+//
+// Parameters:
+//   - sources: Slice of URLs, file paths and/or raw M3U contents.
+//   - checkLive: Boolean flag to check if stream URLs are accessible and working
+//   - enforceSchema: If true, keeps all fields even with empty values; if false, removes keys with empty string values
+//
+// It returns a *SourceError for each source that could not be loaded.
+func (p *M3uParser) ParseSources(sources []string, checkLive bool, enforceSchema bool) []error {
+	p.setup(checkLive, enforceSchema)
+
+	contents := make([]string, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+	for i, source := range sources {
+		go func(i int, source string) {
+			defer wg.Done()
+			contents[i], errs[i] = p.readSource(source)
+		}(i, source)
+	}
+	wg.Wait()
+
+	var sourceErrors []error
+	var streams []Channel
+	for i, source := range sources {
+		name := source
+		if isRawContent(source) {
+			name = fmt.Sprintf("raw:%d", i)
+		}
+		var channels []Channel
+		if errs[i] == nil {
+			channels, errs[i] = p.decodeContent(contents[i])
+		}
+		if errs[i] != nil {
+			log.Warnf("Failed to load source %s: %v", name, errs[i])
+			sourceErrors = append(sourceErrors, &SourceError{Source: name, Err: errs[i]})
+			continue
+		}
+		for _, channel := range channels {
+			channel["source"] = name
+			streams = append(streams, channel)
 		}
 	}
 	if p.CheckLive {
-		bar = pb.StartNew(count)
+		p.checkChannels(streams)
+	}
+	p.load(streams)
+	return sourceErrors
+}
+
+// parseContent parses the m3u content and returns the channels in playlist order.
+func (p *M3uParser) parseContent(content string) []Channel {
+	p.lines = nil
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) != "" {
+			p.lines = append(p.lines, strings.TrimSpace(line))
+		}
+	}
+	if len(p.lines) == 0 {
+		log.Infoln("No content to parse!!!")
+		return nil
+	}
+	header := p.parseHeader(strings.TrimPrefix(p.lines[0], "\uFEFF"))
+	channels := p.parseLines()
+	inheritCatchup(channels, header)
+	return channels
+}
+
+// parseHeader returns the attributes of the #EXTM3U line and records them. With multiple sources
+// the first value wins.
+func (p *M3uParser) parseHeader(line string) map[string]string {
+	header := make(map[string]string)
+	if !strings.HasPrefix(line, "#EXTM3U") {
+		return header
+	}
+	for _, match := range p.regexes["attributes"].FindAllStringSubmatch(line, -1) {
+		if match[2] == "" {
+			continue
+		}
+		header[match[1]] = match[2]
+		if _, ok := p.header[match[1]]; !ok {
+			p.header[match[1]] = match[2]
+		}
+	}
+	return header
+}
+
+// Header returns the attributes of the #EXTM3U header line, eg. url-tvg.
+// With multiple sources the value of the first source having an attribute is used.
+func (p *M3uParser) Header() map[string]string {
+	header := make(map[string]string, len(p.header))
+	for key, value := range p.header {
+		header[key] = value
 	}
-	wg.Add(count)
+	return header
+}
+
+func (p *M3uParser) parseLines() []Channel {
+	re := compileRegex("#EXTINF")
+	parsed := make([]Channel, len(p.lines))
 	for lineNumber := range p.lines {
 		if re.Match([]byte(p.lines[lineNumber])) {
-			go p.parseLine(lineNumber)
+			p.wg.Add(1)
+			go func(lineNumber int) {
+				defer p.wg.Done()
+				parsed[lineNumber] = p.parseLine(lineNumber)
+			}(lineNumber)
 		}
 	}
-	wg.Wait()
-	if p.CheckLive {
-		bar.Finish()
+	p.wg.Wait()
+	var channels []Channel
+	for _, channel := range parsed {
+		if channel != nil {
+			channels = append(channels, channel)
+		}
 	}
+	return channels
 }
 
-func (p *M3uParser) parseLine(lineNumber int) {
-	defer wg.Done()
-	var isFile bool
+func (p *M3uParser) parseLine(lineNumber int) Channel {
 	var streamLink string
 	channel := make(Channel)
 	lineInfo := p.lines[lineNumber]
 
 	for i := range [2]int{1, 2} {
+		if lineNumber+i >= len(p.lines) {
+			break
+		}
 		isUrl := isValidURL(p.lines[lineNumber+i])
 		if isUrl {
 			streamLink = p.lines[lineNumber+i]
 			break
 		} else if p.regexes["file"].Match([]byte(p.lines[lineNumber+i])) {
 			streamLink = p.lines[lineNumber+i]
-			isFile = true
 			break
 		}
 	}
 
-	if lineInfo != "" && streamLink != "" {
-		var countryName string
-
-		tvg := make(map[string]string)
-		tvg["name"] = getByRegex(p.regexes["tvgName"], lineInfo)
-		tvg["id"] = getByRegex(p.regexes["tvgID"], lineInfo)
-		tvg["url"] = getByRegex(p.regexes["tvgURL"], lineInfo)
-		logo := getByRegex(p.regexes["logo"], lineInfo)
-		category := getByRegex(p.regexes["category"], lineInfo)
-		title := getByRegex(p.regexes["title"], lineInfo)
-		countryCode := getByRegex(p.regexes["countryCode"], lineInfo)
-		language := getByRegex(p.regexes["language"], lineInfo)
-		country := countryClient.MapByAlpha2(strings.ToUpper(countryCode))
-		if country == nil {
-			countryName = ""
-		} else {
-			countryName = country.Name
-		}
-		if p.CheckLive {
-			if isFile {
-				bar.Increment()
-				channel["status"] = "GOOD"
-			} else {
-				wg.Add(1)
-				go p.isLive(streamLink, channel)
+	if lineInfo == "" || streamLink == "" {
+		return nil
+	}
+
+	var countryName string
+
+	tvg := make(map[string]string)
+	tvg["name"] = getByRegex(p.regexes["tvgName"], lineInfo)
+	tvg["id"] = getByRegex(p.regexes["tvgID"], lineInfo)
+	tvg["url"] = getByRegex(p.regexes["tvgURL"], lineInfo)
+	logo := getByRegex(p.regexes["logo"], lineInfo)
+	category := getByRegex(p.regexes["category"], lineInfo)
+	title := extinfTitle(lineInfo)
+	countryCode := getByRegex(p.regexes["countryCode"], lineInfo)
+	language := getByRegex(p.regexes["language"], lineInfo)
+	country := countryClient.MapByAlpha2(strings.ToUpper(countryCode))
+	if country == nil {
+		countryName = ""
+	} else {
+		countryName = country.Name
+	}
+	if title != "" || p.enforceSchema {
+		channel["title"] = title
+	}
+	if logo != "" || p.enforceSchema {
+		channel["logo"] = logo
+	}
+	if category != "" || p.enforceSchema {
+		channel["category"] = category
+	}
+	if language != "" || p.enforceSchema {
+		channel["language"] = language
+	}
+	if tvg["id"] != "" || tvg["name"] != "" || tvg["url"] != "" || p.enforceSchema {
+		temp_tvg := make(map[string]string)
+		for key, value := range tvg {
+			if value != "" || p.enforceSchema {
+				temp_tvg[key] = value
 			}
 		}
-		if title != "" || p.enforceSchema {
-			channel["title"] = title
-		}
-		if logo != "" || p.enforceSchema {
-			channel["logo"] = logo
-		}
-		if category != "" || p.enforceSchema {
-			channel["category"] = category
-		}
-		if language != "" || p.enforceSchema {
-			channel["language"] = language
+		channel["tvg"] = temp_tvg
+	}
+	if countryCode != "" || p.enforceSchema {
+		channel["country"] = map[string]string{"code": countryCode, "name": countryName}
+	}
+	attributes := make(map[string]string)
+	for _, match := range p.regexes["attributes"].FindAllStringSubmatch(lineInfo, -1) {
+		if !knownAttributes[match[1]] && match[2] != "" {
+			attributes[match[1]] = match[2]
 		}
-		if tvg["id"] != "" || tvg["name"] != "" || tvg["url"] != "" || p.enforceSchema {
-			temp_tvg := make(map[string]string)
-			for key, value := range tvg {
-				if value != "" || p.enforceSchema {
-					temp_tvg[key] = value
-				}
+	}
+	if len(attributes) > 0 {
+		channel["attributes"] = attributes
+	}
+	if duration, err := strconv.ParseFloat(getByRegex(p.regexes["duration"], lineInfo), 64); err == nil {
+		setDuration(channel, duration)
+	}
+	channel["url"] = streamLink
+	return channel
+}
+
+// extinfTitle returns the title of an #EXTINF line, the text after the first comma outside quoted attribute values.
+func extinfTitle(line string) string {
+	quoted := false
+	for i, c := range line {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return strings.TrimSpace(line[i+1:])
 			}
-			channel["tvg"] = temp_tvg
-		}
-		if countryCode != "" || p.enforceSchema {
-			channel["country"] = map[string]string{"code": countryCode, "name": countryName}
-		}
-		channel["url"] = streamLink
-		p.mutex.Lock()
-		p.streamsInfo = append(p.streamsInfo, channel)
-		p.mutex.Unlock()
-	} else {
-		if p.CheckLive {
-			bar.Increment()
 		}
 	}
+	return ""
 }
 
 // FilterBy filters stream information.
 // It retrieves/removes stream information from streams information slice using filter/s on key.
+// A stream is retrieved/removed once if its value contains any of the filters, case-insensitive.
+// Streams without the key are never matched, so they are kept when removing.
 //
 // Parameters:
-//   - key: Key can be single or nested. eg. key='name', key='language-name'
+//   - key: Key path. eg. key='title', key='country.code', key='attributes.tvg-chno', key='alternatives[0]'. See Path.
 //   - filters: Slice of filter/s to perform the retrieve or remove operation.
 //   - retrieve: True to retrieve and False for removing based on key.
 func (p *M3uParser) FilterBy(key string, filters []string, retrieve bool) {
+	if err := p.FilterWith(key, filters, retrieve, FilterOptions{}); err != nil {
+		log.Warnln(err)
+	}
+}
+
+// FilterWith filters stream information with matching options.
+// It retrieves/removes stream information from streams information slice using filter/s on key.
+// Every stream is kept at most once and streams without the key are never matched.
+//
+// Parameters:
+//   - key: Key path. eg. key='title', key='country.code'. See Path.
+//   - filters: Slice of filter/s to perform the retrieve or remove operation.
+//   - retrieve: True to retrieve and False for removing matching streams.
+//   - opts: How filters are matched against the value of key.
+//
+// It returns an error for an invalid key or regular expression, leaving streams information unchanged.
+func (p *M3uParser) FilterWith(key string, filters []string, retrieve bool, opts FilterOptions) error {
 	if p.isEmpty() {
 		log.Infof("No streams info to filter.")
-		return
+		return nil
 	}
-
 	if len(filters) == 0 {
 		log.Warnln("Filter word/s missing!!!")
-		return
+		return nil
 	}
+	return p.Apply(p.Collection().Filter(key, filters, retrieve, opts))
+}
 
-	var key0, key1 string
-	var filteredStreams []Channel
-	var nestedKey bool
+// ResetOperations resets the stream information slice to initial state before various operations.
+// The reset is recorded in the operations history and can be undone.
+func (p *M3uParser) ResetOperations() {
+	p.Apply(NewCollection(p.streamsInfoBackup))
+}
 
-	splittedKey := strings.Split(key, "-")
-	if len(splittedKey) == 2 {
-		key0, key1 = splittedKey[0], splittedKey[1]
-		nestedKey = true
-	} else if len(splittedKey) > 2 {
-		log.Warnf("Nested key is seperated by multiple key seperator -")
-		return
+// Collection returns the current streams information as an immutable collection.
+// Operations on the collection don't change the parser until the result is passed to Apply.
+func (p *M3uParser) Collection() Collection {
+	return Collection{channels: p.streamsInfo}
+}
+
+// Apply makes a collection the current streams information.
+// The previous streams information is recorded in the operations history for Undo.
+//
+// Parameters:
+//   - collection: Collection usually derived from Collection().
+//
+// It returns the error of the collection if any, leaving streams information unchanged.
+func (p *M3uParser) Apply(collection Collection) error {
+	if collection.err != nil {
+		return collection.err
 	}
+	p.undoHistory = append(p.undoHistory, p.streamsInfo)
+	p.redoHistory = nil
+	p.streamsInfo = collection.channels
+	return nil
+}
 
-	switch nestedKey {
-	case false:
-		for _, stream := range p.streamsInfo {
-			if val, ok := stream[key]; ok {
-				for _, filter := range filters {
-					if retrieve {
-						if strings.Contains(strings.ToLower(fmt.Sprintf("%v", val)), strings.ToLower(filter)) {
-							filteredStreams = append(filteredStreams, stream)
-						}
-					} else {
-						if !strings.Contains(strings.ToLower(fmt.Sprintf("%v", val)), strings.ToLower(filter)) {
-							filteredStreams = append(filteredStreams, stream)
-						}
-					}
-				}
-			}
-		}
-	case true:
-		for _, stream := range p.streamsInfo {
-			if val, ok := stream[key0]; ok {
-				switch v := val.(type) {
-				case map[string]string:
-					if val, ok := v[key1]; ok {
-						for _, filter := range filters {
-							if retrieve {
-								if strings.Contains(strings.ToLower(val), strings.ToLower(filter)) {
-									filteredStreams = append(filteredStreams, stream)
-								}
-							} else {
-								if !strings.Contains(strings.ToLower(val), strings.ToLower(filter)) {
-									filteredStreams = append(filteredStreams, stream)
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+// Undo reverts the last operation on streams information.
+// It returns false if there is no operation to undo.
+func (p *M3uParser) Undo() bool {
+	if len(p.undoHistory) == 0 {
+		return false
 	}
-	p.streamsInfo = filteredStreams
+	p.redoHistory = append(p.redoHistory, p.streamsInfo)
+	p.streamsInfo = p.undoHistory[len(p.undoHistory)-1]
+	p.undoHistory = p.undoHistory[:len(p.undoHistory)-1]
+	return true
 }
 
-// ResetOperations resets the stream information slice to initial state before various operations.
-func (p *M3uParser) ResetOperations() {
-	p.streamsInfo = p.streamsInfoBackup
+// Redo reapplies the last operation reverted by Undo.
+// It returns false if there is no operation to redo.
+func (p *M3uParser) Redo() bool {
+	if len(p.redoHistory) == 0 {
+		return false
+	}
+	p.undoHistory = append(p.undoHistory, p.streamsInfo)
+	p.streamsInfo = p.redoHistory[len(p.redoHistory)-1]
+	p.redoHistory = p.redoHistory[:len(p.redoHistory)-1]
+	return true
 }
 
 // RemoveByExtension removes stream information with certain extension/s.
@@ -362,78 +598,55 @@ func (p *M3uParser) RetrieveByCategory(category []string) {
 
 // SortBy sorts streams information.
 // It sorts streams information slice sorting by key in asc/desc order.
+// The sort is stable and uses natural order, see SortByKeys.
 //
 // Parameters:
-//   - key: It can be single or nested key.
+//   - key: Key path. eg. key='title', key='tvg.id'. See Path.
 //   - asc: Sort by asc or desc order.
 func (p *M3uParser) SortBy(key string, asc bool) {
-	if p.isEmpty() {
-		log.Infof("No streams info to sort.")
-		return
-	}
-
-	var key0, key1 string
-	var nestedKey bool
-
-	splittedKey := strings.Split(key, "-")
-	if len(splittedKey) == 2 {
-		key0, key1 = splittedKey[0], splittedKey[1]
-		nestedKey = true
-	} else if len(splittedKey) > 2 {
-		log.Warnf("Nested key is seperated by multiple key seperator -")
-		return
+	if err := p.SortByKeys([]SortKey{{Key: key, Desc: !asc}}); err != nil {
+		log.Warnln(err)
 	}
+}
 
-	switch nestedKey {
-	case true:
-		value, ok := p.streamsInfo[0][key0].(map[string]string)
-		if ok {
-			if _, ok := value[key1]; ok {
-				sort.Slice(p.streamsInfo, func(i, j int) bool {
-					val1, _ := p.streamsInfo[i][key0].(map[string]string)
-					val2, _ := p.streamsInfo[j][key0].(map[string]string)
-					if asc {
-						return val1[key1] < val2[key1]
-					} else {
-						return val1[key1] > val2[key1]
-					}
-				})
-			}
-
-		}
-	case false:
-		if _, ok := p.streamsInfo[0][key]; ok {
-			sort.Slice(p.streamsInfo, func(i, j int) bool {
-				val1, _ := p.streamsInfo[i][key].(string)
-				val2, _ := p.streamsInfo[j][key].(string)
-				if asc {
-					return val1 < val2
-				} else {
-					return val1 > val2
-				}
-			})
-		}
+// SortByKeys sorts streams information by multiple keys.
+// Streams equal on the first key are sorted by the next key and so on, streams equal on all keys
+// keep their order. Numbers compare numerically and strings in natural, case-insensitive order
+// so "Channel 2" sorts before "Channel 10" and tvg-chno "9" before "10". Streams with missing or
+// empty values sort last in either direction unless MissingFirst is set for the key.
+//
+// Parameters:
+//   - keys: Slice of key paths with their direction.
+//
+// It returns an error for an invalid key, leaving streams information unchanged.
+func (p *M3uParser) SortByKeys(keys []SortKey) error {
+	if p.isEmpty() {
+		log.Infof("No streams info to sort.")
+		return nil
 	}
+	return p.Apply(p.Collection().Sort(keys...))
 }
 
 // GetStreamsSlice gets the parsed streams information slice.
-// It returns the streams information slice.
+// It returns a copy of the streams information slice.
 func (p *M3uParser) GetStreamsSlice() []Channel {
-	return p.streamsInfo
+	return p.Collection().Channels()
 }
 
 // GetStreamsJSON gets the streams information as json.
+// It returns the indented JSONDocument of version JSONSchemaVersion, see Collection.WriteJSON.
 func (p *M3uParser) GetStreamsJSON() string {
-	jsonByte, err := json.Marshal(p.streamsInfo)
-	if err != nil {
+	var content strings.Builder
+	if err := p.Collection().WriteJSON(&content); err != nil {
 		log.Warnln(err)
 		return ""
 	}
-	return string(jsonByte)
+	return content.String()
 }
 
 // GetRandomStream returns a random stream information.
-// It returns a random stream information with shuffle if required.
+// It returns a random stream information with shuffle if required. Without shuffle
+// streams information is not changed, see Random for seeded and weighted selection.
 //
 // Parameters:
 //   - shuffle: To shuffle the streams information slice before returning the random stream information.
@@ -442,74 +655,34 @@ func (p *M3uParser) GetRandomStream(shuffle bool) Channel {
 		log.Infoln("No streams info for random selection.")
 		return Channel{}
 	}
-	rand.Seed(time.Now().UTC().UnixNano())
-	rand.Shuffle(len(p.streamsInfo), func(i, j int) {
-		p.streamsInfo[i], p.streamsInfo[j] = p.streamsInfo[j], p.streamsInfo[i]
-	})
-	return p.streamsInfo[rand.Intn(len(p.streamsInfo))]
+	if shuffle {
+		p.Apply(p.Collection().Shuffle(SampleOptions{}))
+	}
+	return p.Random(SampleOptions{})
 }
 
-// ToFile saves streams information to a json/m3u file.
-// It saves streams information as a JSON/M3U file with a given filename.
+// ToFile saves streams information to a json/m3u/m3u8/pls/xspf/asx/csv/tsv file.
+// It saves streams information as a JSON/M3U/PLS/XSPF/ASX/CSV/TSV file with a given filename,
+// the format is the extension of the filename.
+// JSON files are a JSONDocument of version JSONSchemaVersion with null for missing values.
+// CSV/TSV files have the DefaultCSVColumns and a column for every extra attribute, see WriteCSV to select columns.
 //
 // Parameters:
 //   - filename: Name of the file to save streams information.
 func (p *M3uParser) ToFile(fileName string) {
-	var format string
 	if p.isEmpty() {
 		log.Infoln("No streams info to save.")
 		return
 	}
-	if len(strings.Split(fileName, ".")) > 1 {
-		format = strings.ToLower(strings.Split(fileName, ".")[1])
-	}
+	format := strings.TrimPrefix(filepath.Ext(fileName), ".")
 	log.Infof("Saving to file: %s", fileName)
-	switch format {
-	case "json":
-		json, err := json.MarshalIndent(p.streamsInfo, "", "    ")
-		json = []byte(strings.ReplaceAll(string(json), `: ""`, ": null"))
-		errorLogger(err)
-		if !strings.Contains(fileName, "json") {
-			fileName = fileName + ".json"
-		}
-		err = ioutil.WriteFile(fileName, json, 0644)
-		errorLogger(err)
-	case "m3u":
-		content := []string{"#EXTM3U"}
-		for _, stream := range p.streamsInfo {
-			line := "#EXTINF:-1"
-			if tvg, ok := stream["tvg"]; ok {
-				tvg := tvg.(map[string]string)
-				for key, val := range tvg {
-					if val != "" {
-						line += fmt.Sprintf(` tvg-%s="%s"`, key, val)
-					}
-				}
-			}
-			if logo, ok := stream["logo"]; ok && logo != "" {
-				line += fmt.Sprintf(` tvg-logo="%s"`, logo)
-			}
-			if country, ok := stream["country"]; ok {
-				country := country.(map[string]string)
-				if code, ok := country["code"]; ok && code != "" {
-					line += fmt.Sprintf(` tvg-country="%s"`, code)
-				}
-			}
-			if language, ok := stream["language"]; ok && language != "" {
-				line += fmt.Sprintf(` tvg-language="%s"`, language)
-			}
-			if category, ok := stream["category"]; ok && category != "" {
-				line += fmt.Sprintf(` group-title="%s"`, category)
-			}
-			if title, ok := stream["title"]; ok && title != "" {
-				line += fmt.Sprintf(`,%s`, title)
-			}
-			content = append(content, line)
-			content = append(content, stream["url"].(string))
-		}
-		err := ioutil.WriteFile(fileName, []byte(strings.Join(content, "\n")), 0666)
-		errorLogger(err)
-	default:
+	if !supportedFormat(format) {
 		log.Infoln("File extension not present/supported !!!")
+		return
 	}
+	var content strings.Builder
+	err := p.Collection().WriteFormat(&content, format)
+	errorLogger(err)
+	err = ioutil.WriteFile(fileName, []byte(content.String()), 0644)
+	errorLogger(err)
 }
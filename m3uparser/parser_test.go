@@ -1,11 +1,15 @@
 package m3uparser
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestParseM3uFile(t *testing.T) {
@@ -195,3 +199,84 @@ func TestParseM3uFromRawContentInvalid(t *testing.T) {
 		t.Error("Expected no streams for invalid M3U content.")
 	}
 }
+
+func TestParseM3uReplacesPreviousParse(t *testing.T) {
+	parser := M3uParser{}
+	parser.ParseM3u("#EXTM3U\n#EXTINF:-1,One\nhttp://example.com/1.m3u8", false, false)
+	parser.ParseM3u("#EXTM3U\n#EXTINF:-1,Two\nhttp://example.com/2.m3u8", false, false)
+
+	if len(parser.streamsInfo) != 1 || parser.streamsInfo[0]["title"] != "Two" {
+		t.Errorf("Expected only the second parse, got %v", parser.streamsInfo)
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.UserAgent() != "m3u-test" {
+			http.Error(w, "unexpected user agent", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "#EXTM3U\n#EXTINF:-1,Remote\nhttp://example.com/remote.m3u8")
+	}))
+	defer server.Close()
+
+	fileName := "sources_test.m3u"
+	if err := ioutil.WriteFile(fileName, []byte("#EXTM3U\n#EXTINF:-1,Local\nhttp://example.com/local.m3u8"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	defer os.Remove(fileName)
+
+	parser := M3uParser{UserAgent: "m3u-test"}
+	errs := parser.ParseSources([]string{
+		server.URL,
+		"missing_source.m3u",
+		fileName,
+		"#EXTM3U\n#EXTINF:-1,Raw 1\nhttp://example.com/raw1.m3u8\n#EXTINF:-1,Raw 2\nhttp://example.com/raw2.m3u8",
+	}, false, false)
+
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 source error, got %d", len(errs))
+	}
+	var sourceErr *SourceError
+	if !errors.As(errs[0], &sourceErr) || sourceErr.Source != "missing_source.m3u" {
+		t.Errorf("Expected error for missing_source.m3u, got %v", errs[0])
+	}
+
+	expected := []struct{ title, source string }{
+		{"Remote", server.URL},
+		{"Local", fileName},
+		{"Raw 1", "raw:3"},
+		{"Raw 2", "raw:3"},
+	}
+	streams := parser.GetStreamsSlice()
+	if len(streams) != len(expected) {
+		t.Fatalf("Expected %d streams, got %d", len(expected), len(streams))
+	}
+	for i, e := range expected {
+		if streams[i]["title"] != e.title || streams[i]["source"] != e.source {
+			t.Errorf("Stream %d: expected %s from %s, got %v", i, e.title, e.source, streams[i])
+		}
+	}
+}
+
+func TestParseSourcesTimeout(t *testing.T) {
+	// the server sends the headers and then stalls the body
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n")
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	parser := M3uParser{Timeout: 1}
+	start := time.Now()
+	errs := parser.ParseSources([]string{server.URL}, false, false)
+	if len(errs) != 1 {
+		t.Fatalf("Expected a timeout error, got %v", errs)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Stalled source returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,203 @@
+package m3uparser
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Path - A key path addressing a value inside a channel.
+//
+// Keys are separated by "." and list elements are addressed with [index], negative indexes count
+// from the end. A backslash escapes the next character, so "\." and "\[" are part of a key.
+// eg. "title", "country.code", "attributes.tvg-chno", "alternatives[0]", "attributes.a\.b".
+//
+// For compatibility a path like "language-name" without ".", "[" or "\" addresses the key
+// "language-name" if the channel has it and the nested key "name" of "language" otherwise.
+type Path struct {
+	raw      string
+	segments []pathSegment
+	// legacy holds the nested interpretation of a "key-nested" path.
+	legacy []pathSegment
+}
+
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// ParsePath parses a key path.
+// It returns an error if the path is empty or malformed.
+func ParsePath(path string) (Path, error) {
+	parsed := Path{raw: path}
+	if path == "" {
+		return parsed, fmt.Errorf("empty key path")
+	}
+	if !strings.ContainsAny(path, `.[\`) {
+		parsed.segments = []pathSegment{{key: path}}
+		if splittedKey := strings.Split(path, "-"); len(splittedKey) == 2 && splittedKey[0] != "" && splittedKey[1] != "" {
+			parsed.legacy = []pathSegment{{key: splittedKey[0]}, {key: splittedKey[1]}}
+		}
+		return parsed, nil
+	}
+
+	var key strings.Builder
+	// expectKey is true at the start and after "." where a key must follow.
+	expectKey := true
+	// afterIndex is true after "]" where only ".", "[" or the end may follow.
+	afterIndex := false
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch c {
+		case '\\':
+			if afterIndex {
+				return parsed, fmt.Errorf("key path %q has a key without \".\" after index at position %d", path, i)
+			}
+			if i+1 >= len(path) {
+				return parsed, fmt.Errorf("key path %q ends with escape character", path)
+			}
+			i++
+			key.WriteByte(path[i])
+			expectKey = false
+		case '.':
+			if expectKey {
+				return parsed, fmt.Errorf("key path %q has an empty key at position %d", path, i)
+			}
+			if key.Len() > 0 {
+				parsed.segments = append(parsed.segments, pathSegment{key: key.String()})
+				key.Reset()
+			}
+			expectKey = true
+			afterIndex = false
+		case '[':
+			if key.Len() > 0 {
+				parsed.segments = append(parsed.segments, pathSegment{key: key.String()})
+				key.Reset()
+			} else if len(parsed.segments) == 0 || expectKey {
+				return parsed, fmt.Errorf("key path %q has an index without key at position %d", path, i)
+			}
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return parsed, fmt.Errorf("key path %q has an unterminated index at position %d", path, i)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil {
+				return parsed, fmt.Errorf("key path %q has an invalid index %q", path, path[i+1:i+end])
+			}
+			parsed.segments = append(parsed.segments, pathSegment{index: index, isIndex: true})
+			i += end
+			expectKey = false
+			afterIndex = true
+		default:
+			if afterIndex {
+				return parsed, fmt.Errorf("key path %q has a key without \".\" after index at position %d", path, i)
+			}
+			key.WriteByte(c)
+			expectKey = false
+		}
+	}
+	if expectKey {
+		return parsed, fmt.Errorf("key path %q ends with \".\"", path)
+	}
+	if key.Len() > 0 {
+		parsed.segments = append(parsed.segments, pathSegment{key: key.String()})
+	}
+	return parsed, nil
+}
+
+// MustParsePath is like ParsePath but panics if the path is invalid.
+func MustParsePath(path string) Path {
+	parsed, err := ParsePath(path)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// String returns the path as it was parsed.
+func (p Path) String() string {
+	return p.raw
+}
+
+// Lookup returns the value addressed by the path in channel and whether it exists.
+func (p Path) Lookup(channel Channel) (interface{}, bool) {
+	value, ok := lookupSegments(channel, p.segments)
+	if !ok && p.legacy != nil {
+		return lookupSegments(channel, p.legacy)
+	}
+	return value, ok
+}
+
+func lookupSegments(channel Channel, segments []pathSegment) (interface{}, bool) {
+	var value interface{} = channel
+	for _, segment := range segments {
+		v := reflect.ValueOf(value)
+		for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		switch {
+		case segment.isIndex && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+			index := segment.index
+			if index < 0 {
+				index += v.Len()
+			}
+			if index < 0 || index >= v.Len() {
+				return nil, false
+			}
+			value = v.Index(index).Interface()
+		case !segment.isIndex && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			element := v.MapIndex(reflect.ValueOf(segment.key).Convert(v.Type().Key()))
+			if !element.IsValid() {
+				return nil, false
+			}
+			value = element.Interface()
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Set sets the value addressed by the path in channel, creating nested maps as needed.
+// Nested keys are stored in map[string]string, so the value of a nested key must be a string.
+// A legacy "key-nested" path sets the nested key if channel has a map under key.
+// It returns an error for paths with list indexes or more than two keys.
+func (p Path) Set(channel Channel, value interface{}) error {
+	segments := p.segments
+	if p.legacy != nil {
+		if _, ok := channel[p.legacy[0].key].(map[string]string); ok {
+			segments = p.legacy
+		}
+	}
+	for _, segment := range segments {
+		if segment.isIndex {
+			return fmt.Errorf("can't set key path %q with list index", p.raw)
+		}
+	}
+	switch len(segments) {
+	case 1:
+		channel[segments[0].key] = value
+		return nil
+	case 2:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("can't set non string value of nested key path %q", p.raw)
+		}
+		nested, ok := channel[segments[0].key].(map[string]string)
+		if !ok {
+			if _, exists := channel[segments[0].key]; exists {
+				return fmt.Errorf("can't set key path %q, %q is not a nested key", p.raw, segments[0].key)
+			}
+			nested = make(map[string]string)
+			channel[segments[0].key] = nested
+		}
+		nested[segments[1].key] = str
+		return nil
+	}
+	return fmt.Errorf("can't set key path %q with more than two keys", p.raw)
+}
@@ -0,0 +1,80 @@
+package m3uparser
+
+import (
+	"testing"
+)
+
+func TestPathLookup(t *testing.T) {
+	channel := Channel{
+		"title":        "News",
+		"tvg-chno":     "7",
+		"tvg":          map[string]string{"id": "news.np"},
+		"language":     map[string]string{"name": "Nepali"},
+		"attributes":   map[string]string{"tvg-chno": "12", "a.b": "dotted"},
+		"alternatives": []string{"http://example.com/1", "http://example.com/2"},
+		"probes":       []interface{}{map[string]interface{}{"latency": 120}},
+	}
+
+	tests := []struct {
+		path     string
+		expected interface{}
+		ok       bool
+	}{
+		{"title", "News", true},
+		{"tvg.id", "news.np", true},
+		{"tvg-id", "news.np", true},
+		{"tvg-chno", "7", true},
+		{"language-name", "Nepali", true},
+		{"attributes.tvg-chno", "12", true},
+		{`attributes.a\.b`, "dotted", true},
+		{"alternatives[1]", "http://example.com/2", true},
+		{"alternatives[-1]", "http://example.com/2", true},
+		{"alternatives[2]", nil, false},
+		{"probes[0].latency", 120, true},
+		{"probes[0][0]", nil, false},
+		{"tvg.name", nil, false},
+		{"title.name", nil, false},
+	}
+	for _, test := range tests {
+		path, err := ParsePath(test.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.path, err)
+			continue
+		}
+		value, ok := path.Lookup(channel)
+		if ok != test.ok || value != test.expected {
+			t.Errorf("%s: expected %v %v, got %v %v", test.path, test.expected, test.ok, value, ok)
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{"", "a..b", "a.", ".a", "a[", "a[x]", "[0]", `a\`, "alternatives[0]title", `a[0]\.b`} {
+		if _, err := ParsePath(path); err == nil {
+			t.Errorf("%s: expected error", path)
+		}
+	}
+}
+
+func TestParseExtraAttributes(t *testing.T) {
+	parser := M3uParser{}
+	parser.ParseM3u(`#EXTM3U
+#EXTINF:-1 tvg-id="a.np" tvg-chno="10" catchup="default",Channel 10
+http://example.com/10.m3u8
+#EXTINF:-1 tvg-id="b.np" tvg-chno="2",Channel 2
+http://example.com/2.m3u8`, false, false)
+
+	parser.FilterBy("attributes.tvg-chno", []string{"2"}, true)
+	streams := parser.GetStreamsSlice()
+	if len(streams) != 1 || streams[0]["title"] != "Channel 2" {
+		t.Errorf("Expected Channel 2, got %v", streams)
+	}
+
+	parser.ResetOperations()
+	if err := parser.Where(`attributes.catchup == "default" and tvg.id == "a.np"`); err != nil {
+		t.Fatal(err)
+	}
+	if len(parser.GetStreamsSlice()) != 1 {
+		t.Errorf("Expected 1 stream, got %d", len(parser.GetStreamsSlice()))
+	}
+}
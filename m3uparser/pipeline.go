@@ -0,0 +1,439 @@
+package m3uparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineSpec - A declarative pipeline: sources to load, ordered steps and outputs to write.
+//
+//	{
+//	    "sources": ["https://example.com/np.m3u", "local.m3u"],
+//	    "steps": [
+//	        {"op": "remove", "key": "category", "values": ["religious", "shop"]},
+//	        {"op": "check"},
+//	        {"op": "filter", "key": "status", "values": ["GOOD"], "mode": "exact"},
+//	        {"op": "dedupe", "strategy": "normalized-url", "merge": true},
+//	        {"op": "sort", "by": "category,title"}
+//	    ],
+//	    "outputs": [{"file": "np.m3u"}, {"file": "np.json"}]
+//	}
+//
+// Specs are read from JSON with ReadPipeline, from YAML with ReadPipelineYAML, or from a file
+// with LoadPipeline. YAML specs use the same keys:
+//
+//	sources: [https://example.com/np.m3u]
+//	steps:
+//	  - {op: filter, key: status, values: [GOOD], mode: exact}
+//	  - op: sort
+//	    by: category,title
+//	outputs:
+//	  - file: np.m3u
+type PipelineSpec struct {
+	// Sources are URLs, file paths or raw contents loaded with ParseSources.
+	Sources []string `json:"sources" yaml:"sources"`
+	// CheckLive checks every stream when loading, see the check step to check fewer streams.
+	CheckLive bool `json:"check_live,omitempty" yaml:"check_live"`
+	// EnforceSchema keeps keys with empty values.
+	EnforceSchema bool `json:"enforce_schema,omitempty" yaml:"enforce_schema"`
+	// Timeout in seconds and UserAgent of requests.
+	Timeout   int    `json:"timeout,omitempty" yaml:"timeout"`
+	UserAgent string `json:"user_agent,omitempty" yaml:"user_agent"`
+	// Steps are applied in order.
+	Steps []PipelineStep `json:"steps" yaml:"steps"`
+	// Outputs are written after the steps.
+	Outputs []PipelineOutput `json:"outputs" yaml:"outputs"`
+}
+
+// PipelineStep - A step of a pipeline. Op selects the operation and the parameters it takes:
+//   - filter, remove: key, values, mode, match_all, case_sensitive. Keeps or drops matching streams, see FilterWith.
+//   - where: expr. Keeps streams matching a query expression, see Query.
+//   - dedupe: strategy (default url), merge. See Dedupe and ParseDedupeStrategy.
+//   - sort: by, missing_first. Sorts by comma separated keys, see ParseSortKeys.
+//   - sample: n, seed, per_group. Keeps n random streams, n per group of per_group if set.
+//   - shuffle: seed.
+//   - rename_tvg_ids: renames. See RenameTvgIDs.
+//   - check: Checks the streams so far and sets their status.
+type PipelineStep struct {
+	Op            string            `json:"op" yaml:"op"`
+	Key           string            `json:"key,omitempty" yaml:"key"`
+	Values        []string          `json:"values,omitempty" yaml:"values"`
+	Mode          string            `json:"mode,omitempty" yaml:"mode"`
+	MatchAll      bool              `json:"match_all,omitempty" yaml:"match_all"`
+	CaseSensitive bool              `json:"case_sensitive,omitempty" yaml:"case_sensitive"`
+	Expr          string            `json:"expr,omitempty" yaml:"expr"`
+	Strategy      string            `json:"strategy,omitempty" yaml:"strategy"`
+	Merge         bool              `json:"merge,omitempty" yaml:"merge"`
+	By            string            `json:"by,omitempty" yaml:"by"`
+	MissingFirst  bool              `json:"missing_first,omitempty" yaml:"missing_first"`
+	N             int               `json:"n,omitempty" yaml:"n"`
+	Seed          int64             `json:"seed,omitempty" yaml:"seed"`
+	PerGroup      string            `json:"per_group,omitempty" yaml:"per_group"`
+	Renames       map[string]string `json:"renames,omitempty" yaml:"renames"`
+}
+
+// PipelineOutput - A file written by a pipeline.
+type PipelineOutput struct {
+	File string `json:"file" yaml:"file"`
+	// Format is one of the formats of WriteFormat, defaults to the extension of File.
+	Format string `json:"format,omitempty" yaml:"format"`
+}
+
+// PipelineError - Error of an invalid spec or a failed step, Path locates it in the spec,
+// eg. "steps[2] (sort)".
+type PipelineError struct {
+	Path string
+	Err  error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline %s: %v", e.Path, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// pipelineParams are the parameters taken by every op.
+var pipelineParams = map[string][]string{
+	"filter":         {"key", "values", "mode", "match_all", "case_sensitive"},
+	"remove":         {"key", "values", "mode", "match_all", "case_sensitive"},
+	"where":          {"expr"},
+	"dedupe":         {"strategy", "merge"},
+	"sort":           {"by", "missing_first"},
+	"sample":         {"n", "seed", "per_group"},
+	"shuffle":        {"seed"},
+	"rename_tvg_ids": {"renames"},
+	"check":          {},
+}
+
+// pipelineStage - A compiled step.
+type pipelineStage struct {
+	path  string
+	apply func(c Collection) Collection
+}
+
+// Pipeline - A validated pipeline ready to run, see NewPipeline.
+type Pipeline struct {
+	spec   PipelineSpec
+	stages []pipelineStage
+}
+
+// NewPipeline validates spec and compiles its steps.
+// It returns a *PipelineError naming the offending source, step or output.
+func NewPipeline(spec PipelineSpec) (*Pipeline, error) {
+	for i, source := range spec.Sources {
+		if strings.TrimSpace(source) == "" {
+			return nil, &PipelineError{fmt.Sprintf("sources[%d]", i), fmt.Errorf("empty source")}
+		}
+	}
+	pipeline := &Pipeline{spec: spec}
+	for i, step := range spec.Steps {
+		path := fmt.Sprintf("steps[%d]", i)
+		if step.Op != "" {
+			path += " (" + step.Op + ")"
+		}
+		apply, err := pipeline.compile(step)
+		if err != nil {
+			return nil, &PipelineError{path, err}
+		}
+		pipeline.stages = append(pipeline.stages, pipelineStage{path, apply})
+	}
+	for i, output := range spec.Outputs {
+		path := fmt.Sprintf("outputs[%d]", i)
+		if output.File == "" {
+			return nil, &PipelineError{path, fmt.Errorf("missing file")}
+		}
+		if format := output.format(); !supportedFormat(format) {
+			return nil, &PipelineError{path, fmt.Errorf("unsupported format %q", format)}
+		}
+	}
+	return pipeline, nil
+}
+
+func (o PipelineOutput) format() string {
+	if o.Format != "" {
+		return o.Format
+	}
+	return strings.TrimPrefix(filepath.Ext(o.File), ".")
+}
+
+// checkParams returns an error for the parameters of step that its op doesn't take.
+func checkParams(step PipelineStep) error {
+	params, ok := pipelineParams[step.Op]
+	if !ok {
+		ops := make([]string, 0, len(pipelineParams))
+		for op := range pipelineParams {
+			ops = append(ops, op)
+		}
+		sort.Strings(ops)
+		return fmt.Errorf("unknown op %q, want one of %s", step.Op, strings.Join(ops, ", "))
+	}
+	value := reflect.ValueOf(step)
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "op" || value.Field(i).IsZero() {
+			continue
+		}
+		taken := false
+		for _, param := range params {
+			taken = taken || param == name
+		}
+		if !taken {
+			return fmt.Errorf("%s is not a parameter of %s", name, step.Op)
+		}
+	}
+	return nil
+}
+
+// compile validates step and returns the function applying it.
+func (pl *Pipeline) compile(step PipelineStep) (func(Collection) Collection, error) {
+	if err := checkParams(step); err != nil {
+		return nil, err
+	}
+	switch step.Op {
+	case "filter", "remove":
+		if step.Key == "" || len(step.Values) == 0 {
+			return nil, fmt.Errorf("key and values are required")
+		}
+		if _, err := ParsePath(step.Key); err != nil {
+			return nil, err
+		}
+		opts := FilterOptions{MatchAll: step.MatchAll, CaseSensitive: step.CaseSensitive}
+		if step.Mode != "" {
+			mode, err := ParseMatchMode(step.Mode)
+			if err != nil {
+				return nil, err
+			}
+			opts.Mode = mode
+		}
+		for _, value := range step.Values {
+			if _, err := opts.matcher(value); err != nil {
+				return nil, err
+			}
+		}
+		retrieve := step.Op == "filter"
+		return func(c Collection) Collection {
+			return c.Filter(step.Key, step.Values, retrieve, opts)
+		}, nil
+	case "where":
+		query, err := CompileQuery(step.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return func(c Collection) Collection { return c.Match(query) }, nil
+	case "dedupe":
+		opts := DedupeOptions{Merge: step.Merge}
+		if step.Strategy != "" {
+			strategy, err := ParseDedupeStrategy(step.Strategy)
+			if err != nil {
+				return nil, err
+			}
+			opts.Strategy = strategy
+		}
+		return func(c Collection) Collection { return c.Dedupe(opts) }, nil
+	case "sort":
+		if step.By == "" {
+			return nil, fmt.Errorf("by is required")
+		}
+		keys, err := ParseSortKeys(step.By)
+		if err != nil {
+			return nil, err
+		}
+		for i := range keys {
+			keys[i].MissingFirst = step.MissingFirst
+		}
+		return func(c Collection) Collection { return c.Sort(keys...) }, nil
+	case "sample":
+		if step.N <= 0 {
+			return nil, fmt.Errorf("n must be positive")
+		}
+		opts := SampleOptions{Seed: step.Seed}
+		if step.PerGroup == "" {
+			return func(c Collection) Collection { return c.Sample(step.N, opts) }, nil
+		}
+		if _, err := ParsePath(step.PerGroup); err != nil {
+			return nil, err
+		}
+		return func(c Collection) Collection { return c.SamplePerGroup(step.PerGroup, step.N, opts) }, nil
+	case "shuffle":
+		opts := SampleOptions{Seed: step.Seed}
+		return func(c Collection) Collection { return c.Shuffle(opts) }, nil
+	case "rename_tvg_ids":
+		if len(step.Renames) == 0 {
+			return nil, fmt.Errorf("renames is required")
+		}
+		return func(c Collection) Collection { return c.RenameTvgIDs(step.Renames) }, nil
+	}
+	// check
+	return pl.check, nil
+}
+
+// check returns copies of the channels of c with their status checked.
+func (pl *Pipeline) check(c Collection) Collection {
+	if c.err != nil {
+		return c
+	}
+	checker := &M3uParser{Timeout: pl.spec.Timeout, UserAgent: pl.spec.UserAgent}
+	checker.setup(true, pl.spec.EnforceSchema)
+	checked := make([]Channel, len(c.channels))
+	for i, channel := range c.channels {
+		checked[i] = copyChannel(channel)
+	}
+	checker.checkChannels(checked)
+	return Collection{channels: checked}
+}
+
+// Spec returns the spec of the pipeline.
+func (pl *Pipeline) Spec() PipelineSpec {
+	return pl.spec
+}
+
+// Apply applies the steps to c. A failed step is reported by Err as a *PipelineError.
+func (pl *Pipeline) Apply(c Collection) Collection {
+	for _, stage := range pl.stages {
+		if c.err != nil {
+			return c
+		}
+		c = stage.apply(c)
+		if c.err != nil {
+			return c.with(nil, &PipelineError{stage.path, c.err})
+		}
+	}
+	return c
+}
+
+// WriteOutputs writes c to the outputs of the pipeline.
+func (pl *Pipeline) WriteOutputs(c Collection) error {
+	for i, output := range pl.spec.Outputs {
+		file, err := os.Create(output.File)
+		if err == nil {
+			log.Infof("Saving %d streams to file: %s", c.Len(), output.File)
+			err = c.WriteFormat(file, output.format())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return &PipelineError{fmt.Sprintf("outputs[%d]", i), err}
+		}
+	}
+	return nil
+}
+
+// ReadPipeline reads a JSON spec and validates it, see NewPipeline. Unknown fields are errors.
+func ReadPipeline(r io.Reader) (*Pipeline, error) {
+	var document struct {
+		PipelineSpec
+		Steps []json.RawMessage `json:"steps"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, &PipelineError{"spec", err}
+	}
+	spec := document.PipelineSpec
+	spec.Steps = make([]PipelineStep, len(document.Steps))
+	for i, raw := range document.Steps {
+		stepDecoder := json.NewDecoder(strings.NewReader(string(raw)))
+		stepDecoder.DisallowUnknownFields()
+		if err := stepDecoder.Decode(&spec.Steps[i]); err != nil {
+			return nil, &PipelineError{fmt.Sprintf("steps[%d]", i), err}
+		}
+	}
+	return NewPipeline(spec)
+}
+
+// ReadPipelineYAML reads a YAML spec and validates it, see NewPipeline. The spec is converted to
+// JSON and read with ReadPipeline, so unknown fields are errors and errors have the same paths.
+func ReadPipelineYAML(r io.Reader) (*Pipeline, error) {
+	var document interface{}
+	if err := yaml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, &PipelineError{"spec", err}
+	}
+	content, err := json.Marshal(jsonValue(document))
+	if err != nil {
+		return nil, &PipelineError{"spec", err}
+	}
+	pipeline, err := ReadPipeline(bytes.NewReader(content))
+	if pipelineErr, ok := err.(*PipelineError); ok && strings.HasPrefix(pipelineErr.Err.Error(), "json: ") {
+		// the spec was YAML
+		pipelineErr.Err = errors.New(strings.TrimPrefix(pipelineErr.Err.Error(), "json: "))
+	}
+	return pipeline, err
+}
+
+// jsonValue returns a decoded YAML value with mappings keyed by strings, so it can be encoded as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jsonValue(item)
+		}
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonValue(item)
+		}
+	}
+	return value
+}
+
+// LoadPipeline reads a spec file, YAML if it ends with .yaml or .yml and JSON otherwise, see
+// ReadPipeline and ReadPipelineYAML.
+func LoadPipeline(fileName string) (*Pipeline, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return ReadPipelineYAML(bytes.NewReader(content))
+	}
+	return ReadPipeline(bytes.NewReader(content))
+}
+
+// RunPipeline loads the sources of pipeline, applies its steps and writes its outputs.
+// Sources that fail to load are logged and skipped, it fails if none could be loaded.
+// The result is the current streams information, the steps can be reverted with Undo.
+//
+// Parameters:
+//   - pipeline: Pipeline created with NewPipeline, ReadPipeline or LoadPipeline.
+//
+// It returns a *PipelineError for a failed step or output.
+func (p *M3uParser) RunPipeline(pipeline *Pipeline) error {
+	spec := pipeline.spec
+	if spec.Timeout != 0 {
+		p.Timeout = spec.Timeout
+	}
+	if spec.UserAgent != "" {
+		p.UserAgent = spec.UserAgent
+	}
+	if len(spec.Sources) > 0 {
+		errs := p.ParseSources(spec.Sources, spec.CheckLive, spec.EnforceSchema)
+		if len(errs) == len(spec.Sources) {
+			return &PipelineError{"sources", fmt.Errorf("no source could be loaded: %v", errs[0])}
+		}
+	}
+	if err := p.Apply(pipeline.Apply(p.Collection())); err != nil {
+		return err
+	}
+	return pipeline.WriteOutputs(p.Collection())
+}
@@ -0,0 +1,152 @@
+package m3uparser
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pipelinePlaylist = `#EXTM3U
+#EXTINF:-1 tvg-id="b.np" group-title="News",Beta
+http://example.com/b.m3u8
+#EXTINF:-1 tvg-id="a.np" group-title="News",Alpha
+http://example.com/a.m3u8
+#EXTINF:-1 tvg-id="s.np" group-title="Shop",Shopping
+http://example.com/s.m3u8
+#EXTINF:-1 tvg-id="a.np" group-title="News",Alpha HD
+http://example.com/a.m3u8
+`
+
+func TestRunPipeline(t *testing.T) {
+	dir := t.TempDir()
+	spec := map[string]interface{}{
+		"sources": []string{pipelinePlaylist},
+		"steps": []map[string]interface{}{
+			{"op": "remove", "key": "category", "values": []string{"shop"}},
+			{"op": "dedupe", "strategy": "url"},
+			{"op": "rename_tvg_ids", "renames": map[string]string{"a.np": "alpha"}},
+			{"op": "sort", "by": "title"},
+		},
+		"outputs": []map[string]string{{"file": filepath.Join(dir, "out.m3u")}, {"file": filepath.Join(dir, "out.data"), "format": "json"}},
+	}
+	content, _ := json.Marshal(spec)
+	pipeline, err := ReadPipeline(strings.NewReader(string(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser := M3uParser{}
+	if err := parser.RunPipeline(pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if titles := collectionTitles(parser.GetStreamsSlice()); !reflect.DeepEqual(titles, []string{"Alpha", "Beta"}) {
+		t.Errorf("got titles %v", titles)
+	}
+	if tvg := parser.GetStreamsSlice()[0]["tvg"].(map[string]string); tvg["id"] != "alpha" {
+		t.Errorf("tvg-id not renamed: %v", tvg)
+	}
+	m3u, _ := ioutil.ReadFile(filepath.Join(dir, "out.m3u"))
+	if !strings.HasPrefix(string(m3u), "#EXTM3U") || strings.Count(string(m3u), "#EXTINF") != 2 {
+		t.Errorf("out.m3u:\n%s", m3u)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "out.data"))
+	if err := ValidateJSON(strings.NewReader(string(data))); err != nil {
+		t.Errorf("out.data: %v", err)
+	}
+	if !parser.Undo() || len(parser.GetStreamsSlice()) != 4 {
+		t.Error("pipeline steps can't be undone")
+	}
+
+	// the same spec as a YAML file
+	source := filepath.Join(dir, "in.m3u")
+	ioutil.WriteFile(source, []byte(pipelinePlaylist), 0644)
+	yamlSpec := `sources: [` + source + `]
+steps:
+  - {op: remove, key: category, values: [shop]}
+  - op: dedupe
+    strategy: url
+  - op: rename_tvg_ids
+    renames:
+      a.np: alpha
+  - op: sort
+    by: title
+`
+	specFile := filepath.Join(dir, "pipeline.yml")
+	ioutil.WriteFile(specFile, []byte(yamlSpec), 0644)
+	pipeline, err = LoadPipeline(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pipeline.Spec().Steps[2].Renames, map[string]string{"a.np": "alpha"}) {
+		t.Errorf("YAML steps %+v", pipeline.Spec().Steps)
+	}
+	parser = M3uParser{}
+	if err := parser.RunPipeline(pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if titles := collectionTitles(parser.GetStreamsSlice()); !reflect.DeepEqual(titles, []string{"Alpha", "Beta"}) {
+		t.Errorf("YAML pipeline: got titles %v", titles)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		path string
+		msg  string
+	}{
+		{`{"sources": ["a.m3u"], "stepz": []}`, "spec", "unknown field"},
+		{`{"steps": [{"op": "sort", "by": "title", "extra": 1}]}`, "steps[0]", "unknown field"},
+		{`{"steps": [{"op": "sort", "by": "title"}, {"op": "sort", "by": "title", "values": ["x"]}]}`, "steps[1] (sort)", "values is not a parameter of sort"},
+		{`{"steps": [{"op": "sort"}]}`, "steps[0] (sort)", "by is required"},
+		{`{"steps": [{"op": "sort", "by": "a..b"}]}`, "steps[0] (sort)", "empty key"},
+		{`{"steps": [{"op": "grep"}]}`, "steps[0] (grep)", "unknown op"},
+		{`{"steps": [{"by": "title"}]}`, "steps[0]", "unknown op"},
+		{`{"steps": [{"op": "filter", "key": "title", "values": ["("], "mode": "regex"}]}`, "steps[0] (filter)", "invalid filter"},
+		{`{"steps": [{"op": "filter", "key": "title", "values": ["x"], "mode": "fuzzy"}]}`, "steps[0] (filter)", "unknown match mode"},
+		{`{"steps": [{"op": "where", "expr": "title =="}]}`, "steps[0] (where)", "query error"},
+		{`{"steps": [{"op": "dedupe", "strategy": "title"}]}`, "steps[0] (dedupe)", "unknown dedupe strategy"},
+		{`{"steps": [{"op": "sample"}]}`, "steps[0] (sample)", "n must be positive"},
+		{`{"outputs": [{"file": "out.txt"}]}`, "outputs[0]", "unsupported format"},
+		{`{"sources": [" "]}`, "sources[0]", "empty source"},
+	}
+	// JSON specs are YAML too and give the same errors
+	yamlTests := []struct {
+		spec string
+		path string
+		msg  string
+	}{
+		{"sources: [a.m3u]\nstepz: []\n", "spec", "unknown field"},
+		{"steps:\n  - op: sort\n    by: title\n  - op: sort\n    by: title\n    extra: 1\n", "steps[1]", "unknown field"},
+		{"steps:\n  - op: sample\n", "steps[0] (sample)", "n must be positive"},
+		{"steps: [{op: sort, by: title\n", "spec", "yaml"},
+	}
+	for _, read := range []func(io.Reader) (*Pipeline, error){ReadPipeline, ReadPipelineYAML} {
+		for _, test := range tests {
+			_, err := read(strings.NewReader(test.spec))
+			var pipelineErr *PipelineError
+			if !errors.As(err, &pipelineErr) {
+				t.Errorf("%s: expected a *PipelineError, got %v", test.spec, err)
+				continue
+			}
+			if pipelineErr.Path != test.path || !strings.Contains(err.Error(), test.msg) {
+				t.Errorf("%s: got %v, want %s: ...%s...", test.spec, err, test.path, test.msg)
+			}
+		}
+		tests = yamlTests
+	}
+
+	// a collection that already failed is returned unchanged
+	pipeline, err := NewPipeline(PipelineSpec{Steps: []PipelineStep{{Op: "sample", N: 1, PerGroup: "title"}, {Op: "sort", By: "title"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := pipeline.Apply(Collection{err: errors.New("earlier")})
+	if failed.Err() == nil || failed.Err().Error() != "earlier" {
+		t.Errorf("got %v, want the earlier error", failed.Err())
+	}
+}
@@ -0,0 +1,460 @@
+package m3uparser
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Playlist formats detected by detectFormat and written by ToFile.
+const (
+	formatM3U  = "m3u"
+	formatPLS  = "pls"
+	formatXSPF = "xspf"
+	formatASX  = "asx"
+	formatJSON = "json"
+)
+
+// detectFormat detects the playlist format of content, defaulting to m3u.
+func detectFormat(content string) string {
+	trimmed := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(content, "\uFEFF")))
+	switch {
+	case strings.HasPrefix(trimmed, "#extm3u"):
+		return formatM3U
+	case strings.HasPrefix(trimmed, "[playlist]"):
+		return formatPLS
+	case strings.HasPrefix(trimmed, "["), strings.HasPrefix(trimmed, "{"):
+		return formatJSON
+	case strings.HasPrefix(trimmed, "<"):
+		// skip the xml declaration and comments before the root element
+		for i := strings.Index(trimmed, "<"); i >= 0 && i < len(trimmed); {
+			rest := trimmed[i:]
+			switch {
+			case strings.HasPrefix(rest, "<playlist"):
+				return formatXSPF
+			case strings.HasPrefix(rest, "<asx"):
+				return formatASX
+			case strings.HasPrefix(rest, "<?"), strings.HasPrefix(rest, "<!"):
+				next := strings.Index(rest[1:], "<")
+				if next < 0 {
+					return formatM3U
+				}
+				i += next + 1
+			default:
+				return formatM3U
+			}
+		}
+	}
+	return formatM3U
+}
+
+// decodeContent parses playlist content of any supported format.
+func (p *M3uParser) decodeContent(content string) ([]Channel, error) {
+	switch detectFormat(content) {
+	case formatPLS:
+		return decodePLS(content)
+	case formatXSPF:
+		return decodeXSPF(content)
+	case formatASX:
+		return decodeASX(content)
+	case formatJSON:
+		return ReadJSON(strings.NewReader(strings.TrimPrefix(content, "\uFEFF")), p.enforceSchema)
+	}
+	return p.parseContent(content), nil
+}
+
+// setDuration sets the duration in seconds of channel if it is known, that is positive.
+func setDuration(channel Channel, seconds float64) {
+	if seconds > 0 {
+		channel["duration"] = seconds
+	}
+}
+
+// durationSeconds returns the duration of channel in seconds or -1 if it is unknown.
+func durationSeconds(channel Channel) float64 {
+	if seconds, ok := toFloat(channel["duration"]); ok && seconds > 0 {
+		return seconds
+	}
+	return -1
+}
+
+// formatSeconds formats seconds without a fraction when it is whole.
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
+
+// newPlaylistChannel returns a channel with the non-empty values of a playlist entry.
+func newPlaylistChannel(streamURL, title, logo string, seconds float64) Channel {
+	channel := Channel{"url": strings.TrimSpace(streamURL)}
+	if title = strings.TrimSpace(title); title != "" {
+		channel["title"] = title
+	}
+	if logo = strings.TrimSpace(logo); logo != "" {
+		channel["logo"] = logo
+	}
+	setDuration(channel, seconds)
+	return channel
+}
+
+// decodePLS parses a PLS (Winamp/Shoutcast) playlist.
+func decodePLS(content string) ([]Channel, error) {
+	entries := make(map[int]map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			continue
+		}
+		key, value := strings.ToLower(strings.TrimSpace(line[:separator])), strings.TrimSpace(line[separator+1:])
+		for _, field := range []string{"file", "title", "length"} {
+			if !strings.HasPrefix(key, field) {
+				continue
+			}
+			number, err := strconv.Atoi(key[len(field):])
+			if err != nil {
+				continue
+			}
+			if entries[number] == nil {
+				entries[number] = make(map[string]string)
+			}
+			entries[number][field] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	numbers := make([]int, 0, len(entries))
+	for number := range entries {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	var channels []Channel
+	for _, number := range numbers {
+		entry := entries[number]
+		if entry["file"] == "" {
+			continue
+		}
+		seconds, _ := strconv.ParseFloat(entry["length"], 64)
+		channels = append(channels, newPlaylistChannel(entry["file"], entry["title"], "", seconds))
+	}
+	return channels, nil
+}
+
+type xspfPlaylist struct {
+	XMLName   xml.Name    `xml:"playlist"`
+	Version   string      `xml:"version,attr"`
+	Namespace string      `xml:"xmlns,attr"`
+	Tracks    []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title"`
+	Image    string `xml:"image,omitempty"`
+	// Duration is in milliseconds.
+	Duration int64 `xml:"duration,omitempty"`
+}
+
+// decodeXSPF parses a XSPF (VLC) playlist.
+func decodeXSPF(content string) ([]Channel, error) {
+	var playlist xspfPlaylist
+	if err := xml.Unmarshal([]byte(content), &playlist); err != nil {
+		return nil, fmt.Errorf("invalid xspf playlist: %v", err)
+	}
+	var channels []Channel
+	for _, track := range playlist.Tracks {
+		if strings.TrimSpace(track.Location) == "" {
+			continue
+		}
+		channels = append(channels, newPlaylistChannel(track.Location, track.Title, track.Image, float64(track.Duration)/1000))
+	}
+	return channels, nil
+}
+
+// parseASXDuration parses an ASX duration like "00:01:30.5" into seconds.
+func parseASXDuration(value string) float64 {
+	var seconds float64
+	for _, part := range strings.Split(strings.TrimSpace(value), ":") {
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return -1
+		}
+		seconds = seconds*60 + f
+	}
+	return seconds
+}
+
+// formatASXDuration formats seconds as an ASX duration like "00:01:30.5".
+func formatASXDuration(seconds float64) string {
+	whole := time.Duration(seconds) * time.Second
+	fraction := seconds - math.Floor(seconds)
+	value := fmt.Sprintf("%02d:%02d:%02d", int(whole.Hours()), int(whole.Minutes())%60, int(whole.Seconds())%60)
+	if fraction > 0 {
+		value += strings.TrimPrefix(formatSeconds(math.Round(fraction*1000)/1000), "0")
+	}
+	return value
+}
+
+// decodeASX parses an ASX (Windows Media) playlist. ASX element and attribute names are case-insensitive.
+func decodeASX(content string) ([]Channel, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	attr := func(element xml.StartElement, name string) string {
+		for _, a := range element.Attr {
+			if strings.EqualFold(a.Name.Local, name) {
+				return a.Value
+			}
+		}
+		return ""
+	}
+
+	var channels []Channel
+	var entry map[string]string
+	var path []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid asx playlist: %v", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			path = append(path, name)
+			switch name {
+			case "entry":
+				entry = make(map[string]string)
+			case "ref":
+				if entry != nil && entry["url"] == "" {
+					entry["url"] = attr(t, "href")
+				}
+			case "duration":
+				if entry != nil {
+					entry["duration"] = attr(t, "value")
+				}
+			case "logo":
+				if entry != nil && (entry["logo"] == "" || strings.EqualFold(attr(t, "style"), "icon")) {
+					entry["logo"] = attr(t, "href")
+				}
+			}
+		case xml.CharData:
+			if entry != nil && len(path) > 1 && path[len(path)-1] == "title" && path[len(path)-2] == "entry" {
+				entry["title"] += string(t)
+			}
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			if strings.EqualFold(t.Name.Local, "entry") && entry != nil {
+				if entry["url"] != "" {
+					channels = append(channels, newPlaylistChannel(entry["url"], entry["title"], entry["logo"], parseASXDuration(entry["duration"])))
+				}
+				entry = nil
+			}
+		}
+	}
+	return channels, nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteM3U writes the channels as an M3U playlist.
+func (c Collection) WriteM3U(w io.Writer) error {
+	if c.err != nil {
+		return c.err
+	}
+	content := []string{"#EXTM3U"}
+	for _, stream := range c.channels {
+		line := "#EXTINF:" + formatSeconds(durationSeconds(stream))
+		if tvg, ok := stream["tvg"].(map[string]string); ok {
+			for _, key := range sortedKeys(tvg) {
+				if val := tvg[key]; val != "" {
+					line += fmt.Sprintf(` tvg-%s="%s"`, key, val)
+				}
+			}
+		}
+		if logo, ok := stream["logo"]; ok && logo != "" {
+			line += fmt.Sprintf(` tvg-logo="%s"`, logo)
+		}
+		if country, ok := stream["country"].(map[string]string); ok {
+			if code, ok := country["code"]; ok && code != "" {
+				line += fmt.Sprintf(` tvg-country="%s"`, code)
+			}
+		}
+		if language, ok := stream["language"]; ok && language != "" {
+			line += fmt.Sprintf(` tvg-language="%s"`, language)
+		}
+		if category, ok := stream["category"]; ok && category != "" {
+			line += fmt.Sprintf(` group-title="%s"`, category)
+		}
+		if attributes, ok := stream["attributes"].(map[string]string); ok {
+			for _, key := range sortedKeys(attributes) {
+				line += fmt.Sprintf(` %s="%s"`, key, attributes[key])
+			}
+		}
+		if title, ok := stream["title"]; ok && title != "" {
+			line += fmt.Sprintf(`,%s`, title)
+		}
+		content = append(content, line)
+		streamURL, _ := stream["url"].(string)
+		content = append(content, streamURL)
+	}
+	_, err := io.WriteString(w, strings.Join(content, "\n"))
+	return err
+}
+
+// WritePLS writes the channels as a PLS playlist.
+func (c Collection) WritePLS(w io.Writer) error {
+	if c.err != nil {
+		return c.err
+	}
+	lines := []string{"[playlist]"}
+	for i, stream := range c.channels {
+		streamURL, _ := stream["url"].(string)
+		title, _ := stream["title"].(string)
+		lines = append(lines,
+			fmt.Sprintf("File%d=%s", i+1, streamURL),
+			fmt.Sprintf("Title%d=%s", i+1, title),
+			fmt.Sprintf("Length%d=%s", i+1, formatSeconds(durationSeconds(stream))),
+		)
+	}
+	lines = append(lines, fmt.Sprintf("NumberOfEntries=%d", len(c.channels)), "Version=2", "")
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// WriteXSPF writes the channels as a XSPF playlist.
+func (c Collection) WriteXSPF(w io.Writer) error {
+	if c.err != nil {
+		return c.err
+	}
+	playlist := xspfPlaylist{Version: "1", Namespace: "http://xspf.org/ns/0/"}
+	for _, stream := range c.channels {
+		track := xspfTrack{}
+		track.Location, _ = stream["url"].(string)
+		track.Title, _ = stream["title"].(string)
+		track.Image, _ = stream["logo"].(string)
+		if seconds := durationSeconds(stream); seconds > 0 {
+			track.Duration = int64(math.Round(seconds * 1000))
+		}
+		playlist.Tracks = append(playlist.Tracks, track)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "    ")
+	if err := encoder.Encode(playlist); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type asxPlaylist struct {
+	XMLName xml.Name   `xml:"asx"`
+	Version string     `xml:"version,attr"`
+	Entries []asxEntry `xml:"entry"`
+}
+
+type asxEntry struct {
+	Title    string       `xml:"title,omitempty"`
+	Ref      asxHref      `xml:"ref"`
+	Logo     *asxLogo     `xml:"logo,omitempty"`
+	Duration *asxDuration `xml:"duration,omitempty"`
+}
+
+type asxHref struct {
+	Href string `xml:"href,attr"`
+}
+
+type asxLogo struct {
+	Href  string `xml:"href,attr"`
+	Style string `xml:"style,attr"`
+}
+
+type asxDuration struct {
+	Value string `xml:"value,attr"`
+}
+
+// WriteASX writes the channels as an ASX playlist.
+func (c Collection) WriteASX(w io.Writer) error {
+	if c.err != nil {
+		return c.err
+	}
+	playlist := asxPlaylist{Version: "3.0"}
+	for _, stream := range c.channels {
+		entry := asxEntry{}
+		entry.Title, _ = stream["title"].(string)
+		entry.Ref.Href, _ = stream["url"].(string)
+		if logo, _ := stream["logo"].(string); logo != "" {
+			entry.Logo = &asxLogo{Href: logo, Style: "ICON"}
+		}
+		if seconds := durationSeconds(stream); seconds > 0 {
+			entry.Duration = &asxDuration{Value: formatASXDuration(seconds)}
+		}
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "    ")
+	if err := encoder.Encode(playlist); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// supportedFormat reports whether format is written by WriteFormat.
+func supportedFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case formatJSON, formatM3U, "m3u8", formatPLS, formatXSPF, formatASX, "csv", "tsv":
+		return true
+	}
+	return false
+}
+
+// WriteFormat writes the channels in format: json, m3u, m3u8, pls, xspf, asx, csv or tsv.
+// CSV/TSV have the DefaultCSVColumns and a column for every extra attribute, see WriteCSV to select columns.
+// It returns an error for an unsupported format.
+func (c Collection) WriteFormat(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case formatJSON:
+		return c.WriteJSON(w)
+	case formatM3U, "m3u8":
+		return c.WriteM3U(w)
+	case formatPLS:
+		return c.WritePLS(w)
+	case formatXSPF:
+		return c.WriteXSPF(w)
+	case formatASX:
+		return c.WriteASX(w)
+	case "csv", "tsv":
+		opts := CSVOptions{Columns: csvColumns(c.channels)}
+		if strings.ToLower(format) == "tsv" {
+			opts.Comma = '\t'
+		}
+		return c.WriteCSV(w, opts)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
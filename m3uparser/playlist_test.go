@@ -0,0 +1,142 @@
+package m3uparser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var playlistChannels = []Channel{
+	{"title": "Radio One", "url": "http://example.com/one.mp3", "logo": "http://example.com/one.png", "duration": float64(90.5)},
+	{"title": "Radio & Two", "url": "http://example.com/two?a=1&b=2"},
+}
+
+func TestParsePlaylistFormats(t *testing.T) {
+	sources := map[string]string{
+		"pls": `[playlist]
+File1=http://example.com/one.mp3
+Title1=Radio One
+Length1=90.5
+File2=http://example.com/two?a=1&b=2
+Title2=Radio & Two
+Length2=-1
+NumberOfEntries=2
+Version=2`,
+		"xspf": `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <trackList>
+    <track><location>http://example.com/one.mp3</location><title>Radio One</title><image>http://example.com/one.png</image><duration>90500</duration></track>
+    <track><location>http://example.com/two?a=1&amp;b=2</location><title>Radio &amp; Two</title></track>
+  </trackList>
+</playlist>`,
+		"asx": `<ASX VERSION="3.0">
+  <TITLE>Radios</TITLE>
+  <Entry>
+    <Title>Radio One</Title>
+    <Ref HREF="http://example.com/one.mp3" />
+    <Logo HREF="http://example.com/one.png" STYLE="ICON" />
+    <Duration VALUE="00:01:30.5" />
+  </Entry>
+  <ENTRY><TITLE>Radio &amp; Two</TITLE><REF HREF="http://example.com/two?a=1&amp;b=2"/></ENTRY>
+</ASX>`,
+	}
+	for format, source := range sources {
+		if detected := detectFormat(source); detected != format {
+			t.Errorf("Expected %s to be detected, got %s", format, detected)
+		}
+		parser := M3uParser{}
+		parser.ParseM3u(source, false, false)
+		expected := playlistChannels
+		if format == "pls" {
+			// pls has no logo
+			expected = []Channel{copyChannel(playlistChannels[0]), playlistChannels[1]}
+			delete(expected[0], "logo")
+		}
+		if !reflect.DeepEqual(parser.GetStreamsSlice(), expected) {
+			t.Errorf("%s: expected %v, got %v", format, expected, parser.GetStreamsSlice())
+		}
+	}
+}
+
+func TestPlaylistFormatsRoundTrip(t *testing.T) {
+	parser := M3uParser{}
+	parser.load(playlistChannels)
+	for _, format := range []string{"m3u", "pls", "xspf", "asx"} {
+		fileName := "roundtrip_test." + format
+		parser.ToFile(fileName)
+		decoded := M3uParser{}
+		decoded.ParseM3u(fileName, false, false)
+		os.Remove(fileName)
+
+		expected := playlistChannels
+		if format == "pls" {
+			expected = []Channel{copyChannel(playlistChannels[0]), playlistChannels[1]}
+			delete(expected[0], "logo")
+		}
+		if !reflect.DeepEqual(decoded.GetStreamsSlice(), expected) {
+			t.Errorf("%s: expected %v, got %v", format, expected, decoded.GetStreamsSlice())
+		}
+	}
+}
+
+func TestToFileExtension(t *testing.T) {
+	parser := M3uParser{}
+	parser.load(playlistChannels)
+	dir := t.TempDir()
+	for _, fileName := range []string{
+		filepath.Join(dir, "list.m3u8"),
+		filepath.Join(dir, "my.list.json"),
+		"./tofile_test.m3u",
+	} {
+		defer os.Remove(fileName)
+		parser.ToFile(fileName)
+		decoded := M3uParser{}
+		decoded.ParseM3u(fileName, false, false)
+		if decoded.isEmpty() {
+			t.Errorf("%s: expected the streams to be saved", fileName)
+		}
+	}
+	parser.ToFile(filepath.Join(dir, "list.txt"))
+	if _, err := os.Stat(filepath.Join(dir, "list.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file for an unsupported extension, got %v", err)
+	}
+}
+
+func TestParseM3uTitleAndDuration(t *testing.T) {
+	parser := M3uParser{}
+	parser.ParseM3u(`#EXTM3U
+#EXTINF:120 tvg-id="a.np" group-title="News, Nepal",Channel, A
+http://example.com/a.m3u8`, false, false)
+	streams := parser.GetStreamsSlice()
+	if len(streams) != 1 || streams[0]["title"] != "Channel, A" || streams[0]["category"] != "News, Nepal" || streams[0]["duration"] != float64(120) {
+		t.Errorf("Unexpected streams %v", streams)
+	}
+
+	fileName := "duration_test.m3u"
+	parser.ToFile(fileName)
+	defer os.Remove(fileName)
+	content, _ := ioutil.ReadFile(fileName)
+	expected := "#EXTM3U\n#EXTINF:120 tvg-id=\"a.np\" group-title=\"News, Nepal\",Channel, A\nhttp://example.com/a.m3u8"
+	if string(content) != expected {
+		t.Errorf("Expected %q, got %q", expected, content)
+	}
+}
+
+func TestWriteFormat(t *testing.T) {
+	collection := NewCollection(playlistChannels)
+	var m3u, m3u8 strings.Builder
+	collection.WriteM3U(&m3u)
+	if err := collection.WriteFormat(&m3u8, "M3U8"); err != nil || m3u8.String() != m3u.String() {
+		t.Errorf("m3u8: err %v, got %q", err, m3u8.String())
+	}
+	var tsv strings.Builder
+	if err := collection.WriteFormat(&tsv, "tsv"); err != nil || !strings.HasPrefix(tsv.String(), "title\turl\t") {
+		t.Errorf("tsv: err %v, got %q", err, tsv.String())
+	}
+	if err := collection.WriteFormat(&strings.Builder{}, "txt"); err == nil {
+		t.Error("txt: expected an error")
+	}
+}
@@ -0,0 +1,574 @@
+package m3uparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// QueryError - Error returned for an invalid query expression.
+type QueryError struct {
+	Expr string
+	// Pos is the byte offset in Expr where the error was detected.
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query error at column %d: %s", e.Pos+1, e.Msg)
+}
+
+// Query - A compiled query expression that can be matched against channels.
+//
+// The language supports comparisons of a field with a value combined with and, or, not
+// and parentheses. Fields are key paths of a channel (eg. country.code, alternatives[0]), see Path.
+// Values are double or single quoted strings, numbers, /regex/ literals with optional i, m, s flags,
+// or parenthesized lists for in. Operators:
+//
+//	field == value        equal (numeric when both sides are numbers)
+//	field != value        not equal
+//	field ~ "text"        contains text, case-insensitive
+//	field !~ "text"       does not contain text, case-insensitive
+//	field =~ /regex/i     matches regular expression
+//	field < value         also <=, > and >=
+//	field in (v1, v2)     equal to any of the values, also "not in"
+//	field                 field is present and not empty
+//
+// A list valued field matches when any of its elements matches.
+type Query struct {
+	expr string
+	root queryNode
+}
+
+// CompileQuery compiles a query expression.
+// It returns a *QueryError if the expression is invalid.
+func CompileQuery(expr string) (*Query, error) {
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	qp := &queryParser{expr: expr, tokens: tokens}
+	root, err := qp.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := qp.peek(); tok.kind != tokenEOF {
+		return nil, qp.errorf(tok, "unexpected %s", tok)
+	}
+	return &Query{expr: expr, root: root}, nil
+}
+
+// String returns the source expression of the query.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Match reports whether channel satisfies the query.
+func (q *Query) Match(channel Channel) bool {
+	return q.root.match(channel)
+}
+
+// filterChannels returns the channels matching the query.
+func (q *Query) filterChannels(channels []Channel) []Channel {
+	filtered := []Channel{}
+	for _, channel := range channels {
+		if q.Match(channel) {
+			filtered = append(filtered, channel)
+		}
+	}
+	return filtered
+}
+
+// Where retrieves streams information matching a query expression.
+// See Query for the expression language.
+//
+// Parameters:
+//   - expr: Query expression. eg. `category ~ "news" and country.code in ("NP", "IN") and status == "GOOD"`
+//
+// It returns a *QueryError if the expression is invalid, leaving streams information unchanged.
+func (p *M3uParser) Where(expr string) error {
+	query, err := CompileQuery(expr)
+	if err != nil {
+		return err
+	}
+	if p.isEmpty() {
+		log.Infof("No streams info to filter.")
+		return nil
+	}
+	return p.Apply(p.Collection().Match(query))
+}
+
+// fieldValues returns the values of a field to compare, expanding list values.
+func fieldValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []string:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = v[i]
+		}
+		return values
+	case []interface{}:
+		return v
+	}
+	return []interface{}{value}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenRegex
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenIn
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	// flags holds the flags of a regex literal.
+	flags string
+	pos   int
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	case tokenRegex:
+		return "/" + t.text + "/" + t.flags
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var queryKeywords = map[string]tokenKind{
+	"and": tokenAnd,
+	"or":  tokenOr,
+	"not": tokenNot,
+	"in":  tokenIn,
+}
+
+func isIdentStart(r byte) bool {
+	return r == '_' || unicode.IsLetter(rune(r))
+}
+
+func isIdentChar(r byte) bool {
+	return isIdentStart(r) || unicode.IsDigit(rune(r)) || r == '.' || r == '-' || r == '[' || r == ']'
+}
+
+func lexQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, text: "(", pos: start})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, text: ")", pos: start})
+			i++
+		case c == ',':
+			tokens = append(tokens, queryToken{kind: tokenComma, text: ",", pos: start})
+			i++
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			i++
+			for ; i < len(expr) && expr[i] != c; i++ {
+				if expr[i] == '\\' && i+1 < len(expr) {
+					i++
+				}
+				sb.WriteByte(expr[i])
+			}
+			if i >= len(expr) {
+				return nil, &QueryError{Expr: expr, Pos: start, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, queryToken{kind: tokenString, text: sb.String(), pos: start})
+		case c == '/':
+			var sb strings.Builder
+			i++
+			for ; i < len(expr) && expr[i] != '/'; i++ {
+				if expr[i] == '\\' && i+1 < len(expr) && expr[i+1] == '/' {
+					i++
+				}
+				sb.WriteByte(expr[i])
+			}
+			if i >= len(expr) {
+				return nil, &QueryError{Expr: expr, Pos: start, Msg: "unterminated regular expression"}
+			}
+			i++
+			flagsStart := i
+			for i < len(expr) && strings.IndexByte("ims", expr[i]) >= 0 {
+				i++
+			}
+			if i < len(expr) && isIdentChar(expr[i]) {
+				return nil, &QueryError{Expr: expr, Pos: i, Msg: fmt.Sprintf("unknown regular expression flag %q", expr[i])}
+			}
+			tokens = append(tokens, queryToken{kind: tokenRegex, text: sb.String(), flags: expr[flagsStart:i], pos: start})
+		case strings.IndexByte("=!~<>", c) >= 0:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "=~", "!~", "<=", ">=", "~", "<", ">"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &QueryError{Expr: expr, Pos: start, Msg: fmt.Sprintf("unknown operator %q", c)}
+			}
+			i += len(op)
+			tokens = append(tokens, queryToken{kind: tokenOp, text: op, pos: start})
+		case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+			i++
+			for i < len(expr) && (expr[i] == '.' || (expr[i] >= '0' && expr[i] <= '9')) {
+				i++
+			}
+			if _, err := strconv.ParseFloat(expr[start:i], 64); err != nil {
+				return nil, &QueryError{Expr: expr, Pos: start, Msg: fmt.Sprintf("invalid number %q", expr[start:i])}
+			}
+			tokens = append(tokens, queryToken{kind: tokenNumber, text: expr[start:i], pos: start})
+		case isIdentStart(c) || c == '\\':
+			for i < len(expr) && (isIdentChar(expr[i]) || expr[i] == '\\') {
+				if expr[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i > len(expr) {
+				return nil, &QueryError{Expr: expr, Pos: start, Msg: "field ends with escape character"}
+			}
+			word := expr[start:i]
+			if kind, ok := queryKeywords[strings.ToLower(word)]; ok {
+				tokens = append(tokens, queryToken{kind: kind, text: word, pos: start})
+			} else {
+				tokens = append(tokens, queryToken{kind: tokenIdent, text: word, pos: start})
+			}
+		default:
+			return nil, &QueryError{Expr: expr, Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	tokens = append(tokens, queryToken{kind: tokenEOF, pos: len(expr)})
+	return tokens, nil
+}
+
+type queryParser struct {
+	expr   string
+	tokens []queryToken
+	pos    int
+}
+
+func (qp *queryParser) peek() queryToken {
+	return qp.tokens[qp.pos]
+}
+
+func (qp *queryParser) next() queryToken {
+	tok := qp.tokens[qp.pos]
+	if tok.kind != tokenEOF {
+		qp.pos++
+	}
+	return tok
+}
+
+func (qp *queryParser) errorf(tok queryToken, format string, args ...interface{}) error {
+	return &QueryError{Expr: qp.expr, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (qp *queryParser) parseOr() (queryNode, error) {
+	left, err := qp.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for qp.peek().kind == tokenOr {
+		qp.next()
+		right, err := qp.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (qp *queryParser) parseAnd() (queryNode, error) {
+	left, err := qp.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for qp.peek().kind == tokenAnd {
+		qp.next()
+		right, err := qp.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (qp *queryParser) parseNot() (queryNode, error) {
+	if qp.peek().kind == tokenNot {
+		qp.next()
+		node, err := qp.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	}
+	return qp.parsePrimary()
+}
+
+func (qp *queryParser) parsePrimary() (queryNode, error) {
+	tok := qp.next()
+	switch tok.kind {
+	case tokenLParen:
+		node, err := qp.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := qp.next(); closing.kind != tokenRParen {
+			return nil, qp.errorf(closing, "expected \")\", got %s", closing)
+		}
+		return node, nil
+	case tokenIdent:
+		path, err := ParsePath(tok.text)
+		if err != nil {
+			return nil, qp.errorf(tok, "invalid field: %v", err)
+		}
+		return qp.parseComparison(path)
+	}
+	return nil, qp.errorf(tok, "expected field name, \"not\" or \"(\", got %s", tok)
+}
+
+func (qp *queryParser) parseComparison(field Path) (queryNode, error) {
+	tok := qp.peek()
+	switch tok.kind {
+	case tokenOp:
+		qp.next()
+		value, err := qp.parseValue(tok)
+		if err != nil {
+			return nil, err
+		}
+		node := compareNode{field: field, op: tok.text, value: value}
+		switch tok.text {
+		case "!=":
+			node.op = "=="
+			return notNode{node}, nil
+		case "!~":
+			node.op = "~"
+			return notNode{node}, nil
+		case "=~":
+			node.regex, err = value.compileRegex()
+			if err != nil {
+				return nil, qp.errorf(value.token, "invalid regular expression: %v", err)
+			}
+			return node, nil
+		}
+		if value.token.kind == tokenRegex {
+			return nil, qp.errorf(value.token, "regular expression can only be used with =~")
+		}
+		return node, nil
+	case tokenIn:
+		qp.next()
+		values, err := qp.parseList(tok)
+		if err != nil {
+			return nil, err
+		}
+		return inNode{field: field, values: values}, nil
+	case tokenNot:
+		if qp.pos+1 < len(qp.tokens) && qp.tokens[qp.pos+1].kind == tokenIn {
+			qp.next()
+			inTok := qp.next()
+			values, err := qp.parseList(inTok)
+			if err != nil {
+				return nil, err
+			}
+			return notNode{inNode{field: field, values: values}}, nil
+		}
+	}
+	return existsNode{field: field}, nil
+}
+
+func (qp *queryParser) parseValue(after queryToken) (queryValue, error) {
+	tok := qp.next()
+	switch tok.kind {
+	case tokenString, tokenNumber, tokenRegex:
+		return newQueryValue(tok), nil
+	}
+	return queryValue{}, qp.errorf(tok, "expected value after %s, got %s", after, tok)
+}
+
+func (qp *queryParser) parseList(after queryToken) ([]queryValue, error) {
+	if tok := qp.next(); tok.kind != tokenLParen {
+		return nil, qp.errorf(tok, "expected \"(\" after %s, got %s", after, tok)
+	}
+	var values []queryValue
+	for {
+		tok := qp.next()
+		if tok.kind != tokenString && tok.kind != tokenNumber {
+			return nil, qp.errorf(tok, "expected string or number in list, got %s", tok)
+		}
+		values = append(values, newQueryValue(tok))
+		tok = qp.next()
+		if tok.kind == tokenRParen {
+			return values, nil
+		}
+		if tok.kind != tokenComma {
+			return nil, qp.errorf(tok, "expected \",\" or \")\" in list, got %s", tok)
+		}
+	}
+}
+
+type queryValue struct {
+	token   queryToken
+	number  float64
+	numeric bool
+}
+
+func newQueryValue(tok queryToken) queryValue {
+	value := queryValue{token: tok}
+	if tok.kind == tokenNumber {
+		value.number, _ = strconv.ParseFloat(tok.text, 64)
+		value.numeric = true
+	}
+	return value
+}
+
+func (v queryValue) compileRegex() (*regexp.Regexp, error) {
+	pattern := v.token.text
+	if v.token.flags != "" {
+		pattern = "(?" + v.token.flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// compare returns -1, 0 or 1 comparing field value a with v.
+func (v queryValue) compare(a interface{}) int {
+	if v.numeric {
+		if f, ok := toFloat(a); ok {
+			switch {
+			case f < v.number:
+				return -1
+			case f > v.number:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), v.token.text)
+}
+
+type queryNode interface {
+	match(channel Channel) bool
+}
+
+type andNode struct{ left, right queryNode }
+
+func (n andNode) match(channel Channel) bool {
+	return n.left.match(channel) && n.right.match(channel)
+}
+
+type orNode struct{ left, right queryNode }
+
+func (n orNode) match(channel Channel) bool {
+	return n.left.match(channel) || n.right.match(channel)
+}
+
+type notNode struct{ node queryNode }
+
+func (n notNode) match(channel Channel) bool {
+	return !n.node.match(channel)
+}
+
+type existsNode struct{ field Path }
+
+func (n existsNode) match(channel Channel) bool {
+	value, ok := n.field.Lookup(channel)
+	if !ok || value == nil {
+		return false
+	}
+	for _, v := range fieldValues(value) {
+		if fmt.Sprintf("%v", v) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+type compareNode struct {
+	field Path
+	op    string
+	value queryValue
+	regex *regexp.Regexp
+}
+
+func (n compareNode) match(channel Channel) bool {
+	value, ok := n.field.Lookup(channel)
+	if !ok || value == nil {
+		return false
+	}
+	for _, v := range fieldValues(value) {
+		if n.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n compareNode) matchValue(value interface{}) bool {
+	switch n.op {
+	case "==":
+		return n.value.compare(value) == 0
+	case "~":
+		return strings.Contains(strings.ToLower(fmt.Sprintf("%v", value)), strings.ToLower(n.value.token.text))
+	case "=~":
+		return n.regex.MatchString(fmt.Sprintf("%v", value))
+	case "<":
+		return n.value.compare(value) < 0
+	case "<=":
+		return n.value.compare(value) <= 0
+	case ">":
+		return n.value.compare(value) > 0
+	case ">=":
+		return n.value.compare(value) >= 0
+	}
+	return false
+}
+
+type inNode struct {
+	field  Path
+	values []queryValue
+}
+
+func (n inNode) match(channel Channel) bool {
+	value, ok := n.field.Lookup(channel)
+	if !ok || value == nil {
+		return false
+	}
+	for _, v := range fieldValues(value) {
+		for _, candidate := range n.values {
+			if candidate.compare(v) == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package m3uparser
+
+import (
+	"errors"
+	"testing"
+)
+
+var queryChannels = []Channel{
+	{"title": "Kantipur News", "category": "News", "country": map[string]string{"code": "NP"}, "status": "GOOD", "latency": int64(120)},
+	{"title": "Adult Movies", "category": "News;Movies", "country": map[string]string{"code": "IN"}, "status": "GOOD", "latency": int64(80)},
+	{"title": "Star Sports", "category": "Sports", "country": map[string]string{"code": "IN"}, "status": "BAD"},
+	{"title": "BBC News", "category": "News", "country": map[string]string{"code": "UK"}, "status": "GOOD", "alternatives": []string{"http://example.com/bbc"}},
+}
+
+func TestQueryMatch(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{`category ~ "news" and country.code in ("NP","IN") and status == "GOOD" and not title =~ /adult/i`, []string{"Kantipur News"}},
+		{`status != "GOOD" or country.code == 'UK'`, []string{"Star Sports", "BBC News"}},
+		{`latency < 100`, []string{"Adult Movies"}},
+		{`latency >= 100`, []string{"Kantipur News"}},
+		{`not (category ~ "news")`, []string{"Star Sports"}},
+		{`country.code not in ("IN", "UK")`, []string{"Kantipur News"}},
+		{`alternatives`, []string{"BBC News"}},
+		{`alternatives ~ "bbc"`, []string{"BBC News"}},
+		{`alternatives[0] ~ "bbc" and country\.code == "UK"`, []string{}},
+		{`alternatives[-1] ~ "bbc" and country.code == "UK"`, []string{"BBC News"}},
+		{`title !~ "news"`, []string{"Adult Movies", "Star Sports"}},
+	}
+	for _, test := range tests {
+		query, err := CompileQuery(test.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expr, err)
+			continue
+		}
+		var titles []string
+		for _, channel := range query.filterChannels(queryChannels) {
+			titles = append(titles, channel["title"].(string))
+		}
+		if len(titles) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.expr, test.expected, titles)
+			continue
+		}
+		for i := range titles {
+			if titles[i] != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.expr, test.expected, titles)
+				break
+			}
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{`category ~`, 10},
+		{`category ~ "news`, 11},
+		{`status == "GOOD" and`, 20},
+		{`country.code in "NP"`, 16},
+		{`title =~ /(/`, 9},
+		{`title ~ /news/`, 8},
+		{`(status == "GOOD"`, 17},
+		{`status == "GOOD" title`, 17},
+		{`status # "GOOD"`, 7},
+		{`alternatives[x] == "a"`, 0},
+	}
+	for _, test := range tests {
+		_, err := CompileQuery(test.expr)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%s: expected QueryError, got %v", test.expr, err)
+			continue
+		}
+		if queryErr.Pos != test.pos {
+			t.Errorf("%s: expected error at %d, got %v", test.expr, test.pos, err)
+		}
+	}
+}
+
+func TestWhere(t *testing.T) {
+	parser := M3uParser{streamsInfo: queryChannels}
+	if err := parser.Where(`status ==`); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+	if len(parser.GetStreamsSlice()) != len(queryChannels) {
+		t.Error("Invalid expression must not change streams info")
+	}
+	if err := parser.Where(`status == "GOOD"`); err != nil {
+		t.Fatal(err)
+	}
+	if len(parser.GetStreamsSlice()) != 3 {
+		t.Errorf("Expected 3 streams, got %d", len(parser.GetStreamsSlice()))
+	}
+}
@@ -0,0 +1,150 @@
+package m3uparser
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// WeightFunc - Returns the selection weight of a channel for random selection.
+// Channels with a weight of zero or less are never selected.
+type WeightFunc func(channel Channel) float64
+
+// WeightGood prefers GOOD streams: GOOD streams weigh 1, unchecked streams 0.5 and BAD streams 0.1.
+func WeightGood(channel Channel) float64 {
+	switch channel["status"] {
+	case "GOOD":
+		return 1
+	case "BAD":
+		return 0.1
+	}
+	return 0.5
+}
+
+// WeightLatency prefers GOOD streams with low latency. It is WeightGood divided by
+// one plus the latency in seconds.
+func WeightLatency(channel Channel) float64 {
+	weight := WeightGood(channel)
+	if latency, ok := toFloat(channel["latency"]); ok && latency > 0 {
+		weight /= 1 + latency/1000
+	}
+	return weight
+}
+
+// SampleOptions - Options for random selection.
+type SampleOptions struct {
+	// Seed makes the selection reproducible. Zero uses a time based seed.
+	Seed int64
+	// Weight returns the selection weight of a channel. Nil selects uniformly.
+	Weight WeightFunc
+}
+
+func (opts SampleOptions) rand() *rand.Rand {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// sampleChannels returns n channels selected without replacement. Weighted selection uses
+// the Efraimidis-Spirakis algorithm: each channel gets the key u^(1/weight) and the n largest keys win.
+func sampleChannels(channels []Channel, n int, weight WeightFunc, r *rand.Rand) []Channel {
+	type sampleItem struct {
+		channel Channel
+		key     float64
+	}
+	items := make([]sampleItem, 0, len(channels))
+	for _, channel := range channels {
+		w := 1.0
+		if weight != nil {
+			w = weight(channel)
+		}
+		u := r.Float64()
+		if w <= 0 {
+			continue
+		}
+		items = append(items, sampleItem{channel: channel, key: math.Pow(u, 1/w)})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].key > items[j].key
+	})
+	if n > len(items) {
+		n = len(items)
+	}
+	if n < 0 {
+		n = 0
+	}
+	sampled := make([]Channel, n)
+	for i := range sampled {
+		sampled[i] = items[i].channel
+	}
+	return sampled
+}
+
+// Random returns a random channel or nil if there is no channel to select.
+func (c Collection) Random(opts SampleOptions) Channel {
+	if c.err != nil {
+		return nil
+	}
+	sampled := sampleChannels(c.channels, 1, opts.Weight, opts.rand())
+	if len(sampled) == 0 {
+		return nil
+	}
+	return sampled[0]
+}
+
+// Sample returns up to n distinct random channels in selection order, none if n <= 0.
+func (c Collection) Sample(n int, opts SampleOptions) Collection {
+	if c.err != nil {
+		return c
+	}
+	return Collection{channels: sampleChannels(c.channels, n, opts.Weight, opts.rand())}
+}
+
+// SamplePerGroup returns up to n distinct random channels of every group of key, eg. n channels
+// per category, none if n <= 0. Groups follow the order of GroupBy.
+func (c Collection) SamplePerGroup(key string, n int, opts SampleOptions) Collection {
+	groups, err := c.GroupBy(key)
+	if err != nil {
+		return c.with(nil, err)
+	}
+	r := opts.rand()
+	var sampled []Channel
+	for _, group := range groups {
+		sampled = append(sampled, sampleChannels(group.Channels, n, opts.Weight, r)...)
+	}
+	return Collection{channels: sampled}
+}
+
+// Shuffle returns the channels in random order.
+func (c Collection) Shuffle(opts SampleOptions) Collection {
+	if c.err != nil {
+		return c
+	}
+	shuffled := c.Channels()
+	opts.rand().Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return Collection{channels: shuffled}
+}
+
+// Random returns a random stream information without changing streams information.
+//
+// Parameters:
+//   - opts: Seed for reproducible selection and Weight to prefer some streams, eg. WeightGood.
+//
+// It returns nil if there is no stream to select.
+func (p *M3uParser) Random(opts SampleOptions) Channel {
+	return p.Collection().Random(opts)
+}
+
+// Sample returns up to n distinct random streams information without changing streams information.
+//
+// Parameters:
+//   - n: Number of streams to select.
+//   - opts: Seed for reproducible selection and Weight to prefer some streams, eg. WeightLatency.
+func (p *M3uParser) Sample(n int, opts SampleOptions) []Channel {
+	return p.Collection().Sample(n, opts).channels
+}
@@ -0,0 +1,99 @@
+package m3uparser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func randomChannels() []Channel {
+	var channels []Channel
+	for i := 0; i < 20; i++ {
+		channel := Channel{"title": fmt.Sprintf("Channel %d", i), "category": []string{"News", "Sports"}[i%2], "status": "BAD"}
+		if i == 7 {
+			channel["status"] = "GOOD"
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+func TestSampleReproducible(t *testing.T) {
+	collection := NewCollection(randomChannels())
+	first := collectionTitles(collection.Sample(5, SampleOptions{Seed: 42}).Channels())
+	second := collectionTitles(collection.Sample(5, SampleOptions{Seed: 42}).Channels())
+	assertTitles(t, "seeded sample", second, first...)
+
+	seen := map[string]bool{}
+	for _, title := range first {
+		if seen[title] {
+			t.Errorf("Sample returned %s twice", title)
+		}
+		seen[title] = true
+	}
+	if collection.Sample(50, SampleOptions{Seed: 1}).Len() != 20 {
+		t.Error("Sample larger than collection must return all channels")
+	}
+	assertTitles(t, "collection unchanged", collectionTitles(collection.Channels()), collectionTitles(randomChannels())...)
+}
+
+func TestRandomWeighted(t *testing.T) {
+	collection := NewCollection(randomChannels())
+	weight := func(channel Channel) float64 {
+		if channel["status"] == "GOOD" {
+			return 1
+		}
+		return 0
+	}
+	for seed := int64(1); seed <= 10; seed++ {
+		if channel := collection.Random(SampleOptions{Seed: seed, Weight: weight}); channel["title"] != "Channel 7" {
+			t.Errorf("Expected only GOOD channel to be selected, got %v", channel)
+		}
+	}
+	if channel := NewCollection(nil).Random(SampleOptions{}); channel != nil {
+		t.Errorf("Expected nil from empty collection, got %v", channel)
+	}
+
+	good := 0
+	for seed := int64(1); seed <= 200; seed++ {
+		if collection.Random(SampleOptions{Seed: seed, Weight: WeightGood})["status"] == "GOOD" {
+			good++
+		}
+	}
+	// GOOD weighs 10 times a BAD stream, so it is selected about a third of the time instead of 1 in 20
+	if good < 30 {
+		t.Errorf("Expected GOOD stream to be preferred, selected %d of 200 times", good)
+	}
+}
+
+func TestSamplePerGroup(t *testing.T) {
+	sampled := NewCollection(randomChannels()).SamplePerGroup("category", 2, SampleOptions{Seed: 3})
+	if err := sampled.Err(); err != nil {
+		t.Fatal(err)
+	}
+	groups, _ := sampled.GroupBy("category")
+	if len(groups) != 2 || len(groups[0].Channels) != 2 || len(groups[1].Channels) != 2 {
+		t.Errorf("Expected 2 channels per category, got %v", sampled.Channels())
+	}
+}
+
+func TestSampleNotPositive(t *testing.T) {
+	collection := NewCollection(randomChannels())
+	for _, n := range []int{0, -1} {
+		if sampled := collection.Sample(n, SampleOptions{}); sampled.Err() != nil || sampled.Len() != 0 {
+			t.Errorf("Sample(%d): %d channels, error %v", n, sampled.Len(), sampled.Err())
+		}
+		if sampled := collection.SamplePerGroup("category", n, SampleOptions{}); sampled.Err() != nil || sampled.Len() != 0 {
+			t.Errorf("SamplePerGroup(%d): %d channels, error %v", n, sampled.Len(), sampled.Err())
+		}
+	}
+}
+
+func TestGetRandomStreamWithoutShuffle(t *testing.T) {
+	parser := M3uParser{}
+	parser.load(randomChannels())
+	parser.GetRandomStream(false)
+	assertTitles(t, "unchanged", collectionTitles(parser.GetStreamsSlice()), collectionTitles(randomChannels())...)
+	if parser.Undo() {
+		t.Error("Expected no history entry without shuffle")
+	}
+}
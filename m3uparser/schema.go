@@ -0,0 +1,248 @@
+package m3uparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchemaVersion is the version of the JSON document written by ToFile, GetStreamsJSON and
+// Collection.WriteJSON. It changes whenever the shape of JSONDocument changes.
+const JSONSchemaVersion = 1
+
+// JSONDocument - The versioned JSON document of streams information.
+//
+// Every key is always present, missing and empty values are null.
+// The JSON Schema of the document is in schema/channels.v1.schema.json and returned by JSONSchema.
+type JSONDocument struct {
+	Version  int           `json:"version" description:"Version of the document schema."`
+	Channels []JSONChannel `json:"channels" description:"Streams information in playlist order."`
+}
+
+// JSONChannel - A channel in JSONDocument.
+type JSONChannel struct {
+	Title        *string                `json:"title" description:"Title after the last comma of #EXTINF."`
+	Logo         *string                `json:"logo" description:"tvg-logo attribute."`
+	Category     *string                `json:"category" description:"group-title attribute."`
+	Language     *string                `json:"language" description:"tvg-language attribute."`
+	URL          *string                `json:"url" description:"Stream URL or file path."`
+	Tvg          JSONTvg                `json:"tvg" description:"tvg-id, tvg-name and tvg-url attributes."`
+	Country      JSONCountry            `json:"country" description:"tvg-country attribute and its country name."`
+	Status       *string                `json:"status" description:"GOOD or BAD if the stream was checked."`
+	Latency      *int64                 `json:"latency" description:"Response time of the check in milliseconds."`
+	Duration     *float64               `json:"duration" description:"Duration in seconds, null for live streams."`
+	Source       *string                `json:"source" description:"Source of the stream when parsing multiple sources."`
+	Alternatives []string               `json:"alternatives" description:"Alternative URLs of merged duplicates."`
+	Attributes   map[string]string      `json:"attributes" description:"Other #EXTINF attributes."`
+	Extra        map[string]interface{} `json:"extra" description:"Any other channel keys."`
+}
+
+// JSONTvg - The tvg attributes of a JSONChannel.
+type JSONTvg struct {
+	ID   *string `json:"id" description:"tvg-id attribute."`
+	Name *string `json:"name" description:"tvg-name attribute."`
+	URL  *string `json:"url" description:"tvg-url attribute."`
+}
+
+// JSONCountry - The country of a JSONChannel.
+type JSONCountry struct {
+	Code *string `json:"code" description:"ISO 3166-1 alpha-2 country code."`
+	Name *string `json:"name" description:"Country name."`
+}
+
+// JSONSchema returns the JSON Schema (draft-07) of JSONDocument generated from the Go types.
+func JSONSchema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(JSONDocument{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "go-m3u-parser streams information"
+	properties := schema["properties"].(map[string]interface{})
+	properties["version"].(map[string]interface{})["const"] = JSONSchemaVersion
+	properties["channels"].(map[string]interface{})["type"] = "array"
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(schema); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// nullable allows null in addition to the type of schema.
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	schema["type"] = []interface{}{schema["type"], "null"}
+	return schema
+}
+
+// typeSchema returns the schema of t. Pointers, slices and maps are nullable, structs require all fields.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(typeSchema(t.Elem()))
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice:
+		return nullable(map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())})
+	case reflect.Map:
+		schema := map[string]interface{}{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = typeSchema(t.Elem())
+		}
+		return nullable(schema)
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []interface{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			property := typeSchema(field.Type)
+			if description := field.Tag.Get("description"); description != "" {
+				property["description"] = description
+			}
+			properties[name] = property
+			required = append(required, name)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// ValidateJSON validates a JSON document of streams information against the schema of JSONSchemaVersion.
+// The error names the offending location, eg. "channels[2].tvg.id: expected string or null, got number".
+func ValidateJSON(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("invalid json: %v", err)
+	}
+	var schema map[string]interface{}
+	content, err := JSONSchema()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return err
+	}
+	return validateValue(schema, document, "")
+}
+
+// jsonType returns the JSON Schema type name of a value decoded with UseNumber.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// validateValue validates value against the subset of JSON Schema generated by typeSchema.
+func validateValue(schema map[string]interface{}, value interface{}, location string) error {
+	where := location
+	if where == "" {
+		where = "document"
+	}
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %s", where, fmt.Sprintf(format, args...))
+	}
+
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, name := range t {
+			types = append(types, name.(string))
+		}
+	}
+	actual := jsonType(value)
+	if len(types) > 0 {
+		matched := false
+		for _, name := range types {
+			if name == actual || (name == "number" && actual == "integer") {
+				matched = true
+			}
+		}
+		if !matched {
+			return fail("expected %s, got %s", strings.Join(types, " or "), actual)
+		}
+	}
+	if expected, ok := schema["const"]; ok && fmt.Sprint(expected) != fmt.Sprint(value) {
+		return fail("expected %v, got %v", expected, value)
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		for i, element := range v {
+			if items == nil {
+				break
+			}
+			if err := validateValue(items, element, fmt.Sprintf("%s[%d]", location, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		prefix := location
+		if prefix != "" {
+			prefix += "."
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := properties[key].(map[string]interface{}); ok {
+				if err := validateValue(property, v[key], prefix+key); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fail("unknown key %q", key)
+				}
+			case map[string]interface{}:
+				if err := validateValue(additional, v[key], prefix+key); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				return fail("missing key %q", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package m3uparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SortKey - A key path to sort by with its direction.
+type SortKey struct {
+	// Key is a key path. See Path.
+	Key string
+	// Desc sorts in descending order.
+	Desc bool
+	// MissingFirst puts streams without a value for the key first instead of last.
+	MissingFirst bool
+}
+
+// ParseSortKeys parses a comma separated list of key paths, a leading "-" sorts descending.
+// eg. "category,-latency,title".
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		key := SortKey{Key: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if _, err := ParsePath(key.Key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortChannels returns a copy of channels stably sorted by keys.
+// Numbers compare numerically and strings in natural order with case folding, so "Channel 2" sorts
+// before "Channel 10". Missing and empty values sort last in either direction unless MissingFirst is set.
+func sortChannels(channels []Channel, keys []SortKey) ([]Channel, error) {
+	paths := make([]Path, len(keys))
+	for i, key := range keys {
+		path, err := ParsePath(key.Key)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+
+	// resolve the values once instead of on every comparison
+	type sortItem struct {
+		channel Channel
+		values  []interface{}
+	}
+	items := make([]sortItem, len(channels))
+	for i, channel := range channels {
+		items[i] = sortItem{channel: channel, values: make([]interface{}, len(paths))}
+		for j, path := range paths {
+			if value, ok := path.Lookup(channel); ok && !isEmptyValue(value) {
+				items[i].values[j] = value
+			}
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].values, items[j].values
+		for k, key := range keys {
+			if a[k] == nil || b[k] == nil {
+				if a[k] == nil && b[k] == nil {
+					continue
+				}
+				return (a[k] == nil) == key.MissingFirst
+			}
+			c := compareValues(a[k], b[k])
+			if c == 0 {
+				continue
+			}
+			if key.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	sorted := make([]Channel, len(items))
+	for i, item := range items {
+		sorted[i] = item.channel
+	}
+	return sorted, nil
+}
+
+// isEmptyValue reports whether value is nil, an empty string or an empty list.
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// compareValues returns -1, 0 or 1 comparing a and b.
+// Numbers compare numerically, everything else as strings in natural order.
+// A list compares by its first element.
+func compareValues(a, b interface{}) int {
+	a, b = fieldValues(a)[0], fieldValues(b)[0]
+	aNumber, aOk := numberValue(a)
+	bNumber, bOk := numberValue(b)
+	if aOk && bOk {
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		}
+		return 0
+	}
+	return naturalCompare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// numberValue returns the value of numeric types. Strings are compared naturally instead.
+func numberValue(value interface{}) (float64, bool) {
+	if _, ok := value.(string); ok {
+		return 0, false
+	}
+	return toFloat(value)
+}
+
+// foldRune maps r to a case-insensitive representative.
+func foldRune(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// naturalCompare compares a and b case-insensitively with runs of digits compared as numbers.
+// Strings differing only in case or leading zeros are equal.
+func naturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		ra, sizeA := utf8.DecodeRuneInString(a[i:])
+		rb, sizeB := utf8.DecodeRuneInString(b[j:])
+		if isDigit(ra) && isDigit(rb) {
+			startA, startB := i, j
+			for i < len(a) && isDigit(rune(a[i])) {
+				i++
+			}
+			for j < len(b) && isDigit(rune(b[j])) {
+				j++
+			}
+			numberA := strings.TrimLeft(a[startA:i], "0")
+			numberB := strings.TrimLeft(b[startB:j], "0")
+			if len(numberA) != len(numberB) {
+				if len(numberA) < len(numberB) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(numberA, numberB); c != 0 {
+				return c
+			}
+			continue
+		}
+		if fa, fb := foldRune(ra), foldRune(rb); fa != fb {
+			if fa < fb {
+				return -1
+			}
+			return 1
+		}
+		i += sizeA
+		j += sizeB
+	}
+	switch {
+	case i < len(a):
+		return 1
+	case j < len(b):
+		return -1
+	}
+	return 0
+}
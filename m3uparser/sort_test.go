@@ -0,0 +1,90 @@
+package m3uparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sortedTitles(t *testing.T, channels []Channel, keys ...SortKey) []string {
+	t.Helper()
+	sorted, err := sortChannels(channels, keys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	titles := make([]string, len(sorted))
+	for i, channel := range sorted {
+		titles[i] = channel["title"].(string)
+	}
+	return titles
+}
+
+func assertTitles(t *testing.T, name string, titles []string, expected ...string) {
+	t.Helper()
+	if len(titles) != len(expected) {
+		t.Errorf("%s: expected %v, got %v", name, expected, titles)
+		return
+	}
+	for i := range titles {
+		if titles[i] != expected[i] {
+			t.Errorf("%s: expected %v, got %v", name, expected, titles)
+			return
+		}
+	}
+}
+
+func TestSortChannels(t *testing.T) {
+	channels := []Channel{
+		{"title": "channel 10", "category": "News", "attributes": map[string]string{"tvg-chno": "10"}, "latency": int64(300)},
+		{"title": "Channel 2", "category": "Sports", "attributes": map[string]string{"tvg-chno": "9"}},
+		{"title": "Ébano", "category": "news", "latency": int64(40)},
+		{"title": "ebano 1", "category": "", "attributes": map[string]string{"tvg-chno": "100"}, "latency": int64(100)},
+	}
+
+	assertTitles(t, "natural", sortedTitles(t, channels, SortKey{Key: "title"}),
+		"Channel 2", "channel 10", "ebano 1", "Ébano")
+	assertTitles(t, "numeric strings", sortedTitles(t, channels, SortKey{Key: "attributes.tvg-chno"}),
+		"Channel 2", "channel 10", "ebano 1", "Ébano")
+	assertTitles(t, "numbers desc missing last", sortedTitles(t, channels, SortKey{Key: "latency", Desc: true}),
+		"channel 10", "ebano 1", "Ébano", "Channel 2")
+	assertTitles(t, "missing first", sortedTitles(t, channels, SortKey{Key: "latency", MissingFirst: true}),
+		"Channel 2", "Ébano", "ebano 1", "channel 10")
+	assertTitles(t, "multiple keys stable", sortedTitles(t, channels, SortKey{Key: "category"}, SortKey{Key: "title", Desc: true}),
+		"Ébano", "channel 10", "Channel 2", "ebano 1")
+
+	if _, err := sortChannels(channels, []SortKey{{Key: "a..b"}}); err == nil {
+		t.Error("Expected error for invalid key")
+	}
+}
+
+func TestNaturalCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"Channel 2", "Channel 10", -1},
+		{"channel 2", "Channel 2", 0},
+		{"a01", "a1", 0},
+		{"a", "a1", -1},
+		{"straße", "STRASSE", 1},
+		{"Ωmega", "ωmega", 0},
+	}
+	for _, test := range tests {
+		if c := naturalCompare(test.a, test.b); c != test.expected {
+			t.Errorf("naturalCompare(%q, %q) = %d, expected %d", test.a, test.b, c, test.expected)
+		}
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	keys, err := ParseSortKeys("category, -latency,title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SortKey{{Key: "category"}, {Key: "latency", Desc: true}, {Key: "title"}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if _, err := ParseSortKeys("title,,category"); err == nil {
+		t.Error("Expected error for empty key")
+	}
+}
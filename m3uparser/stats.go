@@ -0,0 +1,251 @@
+package m3uparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Group - Channels sharing the same value of a key.
+type Group struct {
+	// Key is the value of the key, empty for channels without it.
+	Key      string
+	Channels []Channel
+}
+
+// groupChannels groups channels by the string form of value. A list value puts the channel
+// in the group of every element. Groups are sorted by key in natural order with the empty key last.
+func groupChannels(channels []Channel, value func(Channel) []string) []Group {
+	index := make(map[string]int)
+	var groups []Group
+	for _, channel := range channels {
+		keys := value(channel)
+		if len(keys) == 0 {
+			keys = []string{""}
+		}
+		for _, key := range keys {
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, Group{Key: key})
+			}
+			groups[i].Channels = append(groups[i].Channels, channel)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Key == "" || groups[j].Key == "" {
+			return groups[j].Key == "" && groups[i].Key != ""
+		}
+		return naturalCompare(groups[i].Key, groups[j].Key) < 0
+	})
+	return groups
+}
+
+// pathValues returns the non-empty values of path in channel as strings.
+func pathValues(keyPath Path) func(Channel) []string {
+	return func(channel Channel) []string {
+		value, ok := keyPath.Lookup(channel)
+		if !ok || isEmptyValue(value) {
+			return nil
+		}
+		var values []string
+		for _, v := range fieldValues(value) {
+			if s := fmt.Sprintf("%v", v); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+}
+
+// GroupBy groups channels by the value of key. See Path.
+// Groups are ordered by key in natural order and channels without the key are in the last group
+// with an empty key. A list valued key puts a channel in the group of every element.
+func (c Collection) GroupBy(key string) ([]Group, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	keyPath, err := ParsePath(key)
+	if err != nil {
+		return nil, err
+	}
+	return groupChannels(c.channels, pathValues(keyPath)), nil
+}
+
+// GroupBy groups streams information by the value of key.
+//
+// Parameters:
+//   - key: Key path. eg. key='category', key='country.code'. See Path.
+//
+// It returns the groups ordered by key, with streams without the key in the last group with an empty key.
+func (p *M3uParser) GroupBy(key string) ([]Group, error) {
+	return p.Collection().GroupBy(key)
+}
+
+// GroupStats - Counts of a group of channels.
+type GroupStats struct {
+	Key       string  `json:"key"`
+	Count     int     `json:"count"`
+	Good      int     `json:"good"`
+	Bad       int     `json:"bad"`
+	GoodRatio float64 `json:"good_ratio"`
+}
+
+// Stats - Summary of a collection of channels.
+// Ratios are computed over the checked channels, that is channels with GOOD or BAD status.
+type Stats struct {
+	Total         int          `json:"total"`
+	Good          int          `json:"good"`
+	Bad           int          `json:"bad"`
+	Unchecked     int          `json:"unchecked"`
+	GoodRatio     float64      `json:"good_ratio"`
+	MissingLogo   int          `json:"missing_logo"`
+	MissingTvgID  int          `json:"missing_tvg_id"`
+	Categories    []GroupStats `json:"categories"`
+	Countries     []GroupStats `json:"countries"`
+	Languages     []GroupStats `json:"languages"`
+	Statuses      []GroupStats `json:"statuses"`
+	Hosts         []GroupStats `json:"hosts"`
+	Extensions    []GroupStats `json:"extensions"`
+	Schemes       []GroupStats `json:"schemes"`
+	MissingLogos  []Channel    `json:"-"`
+	MissingTvgIDs []Channel    `json:"-"`
+}
+
+func goodRatio(good, bad int) float64 {
+	if good+bad == 0 {
+		return 0
+	}
+	return float64(good) / float64(good+bad)
+}
+
+func groupStats(groups []Group) []GroupStats {
+	stats := make([]GroupStats, 0, len(groups))
+	for _, group := range groups {
+		s := GroupStats{Key: group.Key, Count: len(group.Channels)}
+		for _, channel := range group.Channels {
+			switch channel["status"] {
+			case "GOOD":
+				s.Good++
+			case "BAD":
+				s.Bad++
+			}
+		}
+		s.GoodRatio = goodRatio(s.Good, s.Bad)
+		stats = append(stats, s)
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Count > stats[j].Count
+	})
+	return stats
+}
+
+// streamURL parses the url of channel, local files have the "file" scheme.
+func streamURL(channel Channel) *url.URL {
+	rawURL, _ := channel["url"].(string)
+	if !isValidURL(rawURL) {
+		return &url.URL{Scheme: "file", Path: rawURL}
+	}
+	u, _ := url.Parse(rawURL)
+	return u
+}
+
+// Stats returns a summary of the channels: totals, GOOD/BAD counts, counts per category, country,
+// language, status, host, extension and scheme ordered by count, and channels missing logo or tvg id.
+func (c Collection) Stats() Stats {
+	stats := Stats{Total: len(c.channels)}
+	for _, channel := range c.channels {
+		switch channel["status"] {
+		case "GOOD":
+			stats.Good++
+		case "BAD":
+			stats.Bad++
+		default:
+			stats.Unchecked++
+		}
+		if logo, _ := channel["logo"].(string); logo == "" {
+			stats.MissingLogos = append(stats.MissingLogos, channel)
+		}
+		if tvg, _ := channel["tvg"].(map[string]string); tvg["id"] == "" {
+			stats.MissingTvgIDs = append(stats.MissingTvgIDs, channel)
+		}
+	}
+	stats.GoodRatio = goodRatio(stats.Good, stats.Bad)
+	stats.MissingLogo = len(stats.MissingLogos)
+	stats.MissingTvgID = len(stats.MissingTvgIDs)
+
+	stats.Categories = groupStats(groupChannels(c.channels, pathValues(MustParsePath("category"))))
+	stats.Countries = groupStats(groupChannels(c.channels, pathValues(MustParsePath("country.code"))))
+	stats.Languages = groupStats(groupChannels(c.channels, pathValues(MustParsePath("language"))))
+	stats.Statuses = groupStats(groupChannels(c.channels, pathValues(MustParsePath("status"))))
+	stats.Hosts = groupStats(groupChannels(c.channels, func(channel Channel) []string {
+		return []string{strings.ToLower(streamURL(channel).Hostname())}
+	}))
+	stats.Extensions = groupStats(groupChannels(c.channels, func(channel Channel) []string {
+		return []string{strings.ToLower(strings.TrimPrefix(path.Ext(streamURL(channel).Path), "."))}
+	}))
+	stats.Schemes = groupStats(groupChannels(c.channels, func(channel Channel) []string {
+		return []string{strings.ToLower(streamURL(channel).Scheme)}
+	}))
+	return stats
+}
+
+// Stats returns a summary of the streams information. See Collection.Stats.
+func (p *M3uParser) Stats() Stats {
+	return p.Collection().Stats()
+}
+
+// WriteJSON writes the stats as indented JSON.
+func (s Stats) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(s)
+}
+
+// WriteTable writes the stats as text tables.
+func (s Stats) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Total\t%d\n", s.Total)
+	fmt.Fprintf(tw, "Good\t%d\n", s.Good)
+	fmt.Fprintf(tw, "Bad\t%d\n", s.Bad)
+	fmt.Fprintf(tw, "Unchecked\t%d\n", s.Unchecked)
+	fmt.Fprintf(tw, "Good ratio\t%.1f%%\n", s.GoodRatio*100)
+	fmt.Fprintf(tw, "Missing logo\t%d\n", s.MissingLogo)
+	fmt.Fprintf(tw, "Missing tvg id\t%d\n", s.MissingTvgID)
+	sections := []struct {
+		name  string
+		stats []GroupStats
+	}{
+		{"Category", s.Categories},
+		{"Country", s.Countries},
+		{"Language", s.Languages},
+		{"Status", s.Statuses},
+		{"Host", s.Hosts},
+		{"Extension", s.Extensions},
+		{"Scheme", s.Schemes},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(tw, "\n%s\tCount\tGood\tBad\tGood ratio\n", section.name)
+		for _, group := range section.stats {
+			key := group.Key
+			if key == "" {
+				key = "(none)"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\n", key, group.Count, group.Good, group.Bad, group.GoodRatio*100)
+		}
+	}
+	return tw.Flush()
+}
+
+// String returns the stats as text tables.
+func (s Stats) String() string {
+	var sb strings.Builder
+	s.WriteTable(&sb)
+	return sb.String()
+}
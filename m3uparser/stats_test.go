@@ -0,0 +1,78 @@
+package m3uparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var statsChannels = []Channel{
+	{"title": "A", "category": "News", "url": "https://a.example.com/live.m3u8", "status": "GOOD", "logo": "http://a.example.com/logo.png", "tvg": map[string]string{"id": "a.np"}},
+	{"title": "B", "category": "News", "url": "http://b.example.com/live.m3u8", "status": "BAD"},
+	{"title": "C", "category": "Sports", "url": "http://b.example.com/live.ts", "status": "GOOD", "country": map[string]string{"code": "NP"}},
+	{"title": "D", "url": "/home/user/movie.mp4"},
+}
+
+func TestGroupBy(t *testing.T) {
+	groups, err := NewCollection(statsChannels).GroupBy("category")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		key   string
+		count int
+	}{{"News", 2}, {"Sports", 1}, {"", 1}}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d", len(expected), len(groups))
+	}
+	for i, e := range expected {
+		if groups[i].Key != e.key || len(groups[i].Channels) != e.count {
+			t.Errorf("Group %d: expected %s with %d, got %s with %d", i, e.key, e.count, groups[i].Key, len(groups[i].Channels))
+		}
+	}
+	if _, err := NewCollection(statsChannels).GroupBy("a..b"); err == nil {
+		t.Error("Expected error for invalid key")
+	}
+}
+
+func TestStats(t *testing.T) {
+	stats := NewCollection(statsChannels).Stats()
+	if stats.Total != 4 || stats.Good != 2 || stats.Bad != 1 || stats.Unchecked != 1 {
+		t.Errorf("Unexpected totals %+v", stats)
+	}
+	if stats.MissingLogo != 3 || stats.MissingTvgID != 3 {
+		t.Errorf("Unexpected missing counts %d %d", stats.MissingLogo, stats.MissingTvgID)
+	}
+	if stats.Categories[0].Key != "News" || stats.Categories[0].Count != 2 || stats.Categories[0].GoodRatio != 0.5 {
+		t.Errorf("Unexpected category stats %+v", stats.Categories[0])
+	}
+	if stats.Hosts[0].Key != "b.example.com" || stats.Hosts[0].Count != 2 {
+		t.Errorf("Unexpected host stats %+v", stats.Hosts)
+	}
+	if stats.Extensions[0].Key != "m3u8" || stats.Extensions[0].Count != 2 {
+		t.Errorf("Unexpected extension stats %+v", stats.Extensions)
+	}
+	schemes := map[string]int{}
+	for _, scheme := range stats.Schemes {
+		schemes[scheme.Key] = scheme.Count
+	}
+	if schemes["http"] != 2 || schemes["https"] != 1 || schemes["file"] != 1 {
+		t.Errorf("Unexpected scheme stats %+v", stats.Schemes)
+	}
+
+	var buf bytes.Buffer
+	if err := stats.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded["total"] != float64(4) {
+		t.Errorf("Unexpected JSON total %v", decoded["total"])
+	}
+	if table := stats.String(); !strings.Contains(table, "b.example.com") || !strings.Contains(table, "(none)") {
+		t.Errorf("Unexpected table:\n%s", table)
+	}
+}
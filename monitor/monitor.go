@@ -0,0 +1,223 @@
+// Package monitor re-parses and re-checks playlists on a schedule and notifies sinks of the
+// changes: channels going down, coming back, added to or removed from the sources.
+//
+//	m := monitor.New(monitor.Config{
+//		Sources:  []string{"https://example.com/np.m3u"},
+//		Interval: 15 * time.Minute,
+//		Sinks:    []monitor.Sink{monitor.Webhook{URL: "https://example.com/hooks/iptv"}},
+//	})
+//	if _, err := m.Check(); err != nil {
+//		log.Fatal(err)
+//	}
+//	m.Run(ctx)
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+	"github.com/pawanpaudel93/go-m3u-parser/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// EventType - The kind of change of an Event.
+type EventType string
+
+const (
+	// ChannelDown is emitted when a GOOD channel is checked BAD.
+	ChannelDown EventType = "down"
+	// ChannelUp is emitted when a BAD channel is checked GOOD again.
+	ChannelUp EventType = "up"
+	// ChannelAdded is emitted for a channel new in the sources.
+	ChannelAdded EventType = "added"
+	// ChannelRemoved is emitted for a channel no longer in the sources.
+	ChannelRemoved EventType = "removed"
+)
+
+// Event - A change of a channel between two checks.
+type Event struct {
+	Type  EventType `json:"type"`
+	Time  time.Time `json:"time"`
+	Title string    `json:"title"`
+	URL   string    `json:"url"`
+	TvgID string    `json:"tvg_id,omitempty"`
+	// Status of the channel after the change, empty for removed channels.
+	Status string `json:"status,omitempty"`
+	// Channel is the channel after the change, before it for removed channels.
+	Channel m3uparser.Channel `json:"-"`
+}
+
+// newEvent returns an event of channel.
+func newEvent(eventType EventType, at time.Time, channel m3uparser.Channel) Event {
+	event := Event{Type: eventType, Time: at, Channel: channel}
+	event.Title, _ = channel["title"].(string)
+	event.URL, _ = channel["url"].(string)
+	if tvg, ok := channel["tvg"].(map[string]string); ok {
+		event.TvgID = tvg["id"]
+	}
+	if eventType != ChannelRemoved {
+		event.Status, _ = channel["status"].(string)
+	}
+	return event
+}
+
+// Config - Configuration of a Monitor.
+type Config struct {
+	// Sources are URLs, file paths or raw playlists merged with M3uParser.ParseSources.
+	Sources []string
+	// Interval between checks of Run.
+	Interval time.Duration
+	// Timeout and UserAgent of the liveness checks, see M3uParser.
+	Timeout   int
+	UserAgent string
+	// Identities matching the channels of two checks, see m3uparser.DiffOptions.
+	Identities []m3uparser.DiffIdentity
+	// Store, if set, records every check, see store.Store.Record. It is saved after every check.
+	Store *store.Store
+	// Sinks are notified of the events of every check.
+	Sinks []Sink
+}
+
+// Monitor - Checks the channels of its sources on a schedule and notifies sinks of the changes.
+type Monitor struct {
+	config Config
+
+	mutex    sync.Mutex
+	previous *m3uparser.Collection
+}
+
+// New returns a monitor of config. Call Check to check the sources and Run to check them on schedule.
+func New(config Config) *Monitor {
+	return &Monitor{config: config}
+}
+
+// Check parses and checks the sources, and sends the changes since the previous check to the
+// sinks. The first check only records the channels and has no events.
+//
+// The channels of a source failing to load are not reported as removed, they are compared again
+// by the next check. It returns the events and an error if no source could be loaded, in which
+// case the previous check is kept, or if the store could not be saved or a sink failed. Every
+// sink is sent the events even if the store or another sink fails.
+func (m *Monitor) Check() ([]Event, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	parser := m3uparser.M3uParser{Timeout: m.config.Timeout, UserAgent: m.config.UserAgent}
+	errs := parser.ParseSources(m.config.Sources, true, false)
+	if len(errs) == len(m.config.Sources) && len(errs) > 0 {
+		return nil, fmt.Errorf("no source could be loaded: %v", errs[0])
+	}
+	now := time.Now()
+	current := parser.Collection()
+	var failures []string
+	if m.config.Store != nil {
+		if err := m.config.Store.Record(current, now); err != nil {
+			return nil, err
+		}
+		if err := m.config.Store.Save(); err != nil {
+			failures = append(failures, fmt.Sprintf("failed to save store: %v", err))
+		}
+	}
+	if m.previous == nil {
+		m.previous = &current
+		log.Infof("Monitoring %d streams", current.Len())
+		return nil, joinFailures(failures)
+	}
+
+	// the channels of sources failing to load are kept for the next check instead of being removed
+	failed := make(map[string]bool)
+	for _, err := range errs {
+		if sourceErr, ok := err.(*m3uparser.SourceError); ok {
+			failed[sourceErr.Source] = true
+		}
+	}
+	var compared, kept []m3uparser.Channel
+	for _, channel := range m.previous.Channels() {
+		if source, _ := channel["source"].(string); failed[source] {
+			kept = append(kept, channel)
+			continue
+		}
+		compared = append(compared, channel)
+	}
+	previous := m3uparser.NewCollection(compared)
+	next := m3uparser.NewCollection(append(current.Channels(), kept...))
+	m.previous = &next
+
+	changes, err := m3uparser.Diff(previous, current, m3uparser.DiffOptions{
+		Identities: m.config.Identities,
+		Fields:     []string{"status"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	for _, change := range changes.Changed {
+		switch {
+		case change.Old["status"] == "GOOD" && change.New["status"] == "BAD":
+			events = append(events, newEvent(ChannelDown, now, change.New))
+		case change.Old["status"] == "BAD" && change.New["status"] == "GOOD":
+			events = append(events, newEvent(ChannelUp, now, change.New))
+		}
+	}
+	for _, channel := range changes.Added {
+		events = append(events, newEvent(ChannelAdded, now, channel))
+	}
+	for _, channel := range changes.Removed {
+		events = append(events, newEvent(ChannelRemoved, now, channel))
+	}
+	log.Infof("Checked %d streams: %d events", current.Len(), len(events))
+	if len(events) == 0 {
+		return nil, joinFailures(failures)
+	}
+	if err := m.notify(events); err != nil {
+		failures = append(failures, err.Error())
+	}
+	return events, joinFailures(failures)
+}
+
+// notify sends events to every sink and returns the errors of the failed ones.
+func (m *Monitor) notify(events []Event) error {
+	var failures []string
+	for _, sink := range m.config.Sinks {
+		if err := sink.Send(events); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to notify: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// joinFailures returns an error of the failures of a check, nil if there are none.
+func joinFailures(failures []string) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(failures, "; "))
+}
+
+// Run checks the sources every Interval until ctx is done. Failed checks, including failures to
+// save the store or notify a sink, are logged as errors. Call Check
+// first to record the channels compared by the first scheduled check.
+func (m *Monitor) Run(ctx context.Context) {
+	if m.config.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Check(); err != nil {
+				log.Errorf("Failed to check sources: %v", err)
+			}
+		}
+	}
+}
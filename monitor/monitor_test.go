@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// streamServer serves streams, the streams in down close the connection.
+type streamServer struct {
+	mutex sync.Mutex
+	down  map[string]bool
+}
+
+func (s *streamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	down := s.down[r.URL.Path]
+	s.mutex.Unlock()
+	if down {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+		return
+	}
+	w.Write([]byte("stream"))
+}
+
+func (s *streamServer) setDown(paths ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.down = make(map[string]bool)
+	for _, path := range paths {
+		s.down[path] = true
+	}
+}
+
+func writePlaylist(t *testing.T, fileName, baseURL string, names ...string) {
+	content := "#EXTM3U\n"
+	for _, name := range names {
+		content += fmt.Sprintf("#EXTINF:-1 tvg-id=\"%s.np\",%s\n%s/%s\n", name, name, baseURL, name)
+	}
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type received struct {
+	Type  EventType
+	Title string
+}
+
+func summarize(events []Event) []received {
+	var summary []received
+	for _, event := range events {
+		summary = append(summary, received{event.Type, event.Title})
+	}
+	return summary
+}
+
+func TestMonitor(t *testing.T) {
+	streams := &streamServer{}
+	streamsServer := httptest.NewServer(streams)
+	defer streamsServer.Close()
+
+	var webhookMutex sync.Mutex
+	var posted []received
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Events []Event }
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		webhookMutex.Lock()
+		posted = append(posted, summarize(body.Events)...)
+		webhookMutex.Unlock()
+	}))
+	defer webhook.Close()
+
+	dir := t.TempDir()
+	playlist, lines := filepath.Join(dir, "playlist.m3u"), filepath.Join(dir, "events.jsonl")
+	var called []received
+	m := New(Config{
+		Sources: []string{playlist},
+		Timeout: 2,
+		Sinks: []Sink{
+			SinkFunc(func(events []Event) error {
+				called = append(called, summarize(events)...)
+				return nil
+			}),
+			&JSONLines{FileName: lines},
+			Webhook{URL: webhook.URL, Header: http.Header{"X-Token": {"secret"}}},
+		},
+	})
+
+	checks := []struct {
+		names  []string
+		down   []string
+		events []received
+	}{
+		// the first check has no events, even for channels already down
+		{[]string{"Alpha", "Beta", "Gamma"}, []string{"/Gamma"}, nil},
+		{[]string{"Alpha", "Beta", "Gamma", "Delta"}, []string{"/Beta"}, []received{
+			{ChannelDown, "Beta"}, {ChannelUp, "Gamma"}, {ChannelAdded, "Delta"},
+		}},
+		{[]string{"Beta", "Gamma", "Delta"}, []string{"/Beta"}, []received{{ChannelRemoved, "Alpha"}}},
+		{[]string{"Beta", "Gamma", "Delta"}, nil, []received{{ChannelUp, "Beta"}}},
+	}
+	var all []received
+	for i, check := range checks {
+		writePlaylist(t, playlist, streamsServer.URL, check.names...)
+		streams.setDown(check.down...)
+		events, err := m.Check()
+		if err != nil {
+			t.Fatalf("Check %d: %v", i, err)
+		}
+		if got := summarize(events); !reflect.DeepEqual(got, check.events) {
+			t.Errorf("Check %d: events %v, want %v", i, got, check.events)
+		}
+		all = append(all, check.events...)
+	}
+
+	if !reflect.DeepEqual(called, all) {
+		t.Errorf("Callback got %v, want %v", called, all)
+	}
+	if !reflect.DeepEqual(posted, all) {
+		t.Errorf("Webhook got %v, want %v", posted, all)
+	}
+	file, err := os.Open(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var written []received
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		if event.URL == "" || event.Time.IsZero() {
+			t.Errorf("Incomplete event %q", scanner.Text())
+		}
+		written = append(written, received{event.Type, event.Title})
+	}
+	if !reflect.DeepEqual(written, all) {
+		t.Errorf("JSON lines %v, want %v", written, all)
+	}
+
+	// a missing source keeps the previous check, a failing webhook is reported
+	os.Remove(playlist)
+	if _, err := m.Check(); err == nil {
+		t.Error("Expected an error for a missing source")
+	}
+	writePlaylist(t, playlist, streamsServer.URL, "Beta", "Gamma")
+	m.config.Sinks = []Sink{Webhook{URL: webhook.URL}}
+	events, err := m.Check()
+	if len(events) != 1 || events[0].Type != ChannelRemoved || events[0].Title != "Delta" {
+		t.Errorf("Events after a failed check %v", summarize(events))
+	}
+	if err == nil {
+		t.Error("Expected an error for a rejected webhook")
+	}
+
+	// the channels of a source failing to load are neither removed nor added back
+	other := filepath.Join(dir, "other.m3u")
+	writePlaylist(t, other, streamsServer.URL, "Epsilon")
+	m = New(Config{Sources: []string{playlist, other}, Timeout: 2})
+	for i, exists := range []bool{true, false, true} {
+		if !exists {
+			os.Remove(other)
+		} else {
+			writePlaylist(t, other, streamsServer.URL, "Epsilon")
+		}
+		if events, err := m.Check(); err != nil || len(events) != 0 {
+			t.Errorf("Check %d with a failing source: events %v, error %v", i, summarize(events), err)
+		}
+	}
+}
+
+func TestMonitorFailures(t *testing.T) {
+	streams := &streamServer{}
+	streamsServer := httptest.NewServer(streams)
+	defer streamsServer.Close()
+	dir := t.TempDir()
+	playlist := filepath.Join(dir, "playlist.m3u")
+	writePlaylist(t, playlist, streamsServer.URL, "Alpha")
+
+	// the store is saved in a missing directory
+	st, err := store.Open(filepath.Join(dir, "missing", "store.json"), store.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent int
+	m := New(Config{
+		Sources:  []string{playlist},
+		Interval: 10 * time.Millisecond,
+		Timeout:  2,
+		Store:    st,
+		Sinks: []Sink{SinkFunc(func(events []Event) error {
+			sent++
+			return errors.New("sink down")
+		})},
+	})
+	if _, err := m.Check(); err == nil || !strings.Contains(err.Error(), "failed to save store") {
+		t.Errorf("Expected a store error, got %v", err)
+	}
+	writePlaylist(t, playlist, streamsServer.URL, "Alpha", "Beta")
+	events, err := m.Check()
+	if len(events) != 1 || sent != 1 {
+		t.Errorf("Events %v sent %d times after a store error", summarize(events), sent)
+	}
+	if err == nil || !strings.Contains(err.Error(), "failed to save store") || !strings.Contains(err.Error(), "sink down") {
+		t.Errorf("Expected store and sink errors, got %v", err)
+	}
+
+	// the failures of scheduled checks are logged as errors
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	log.SetLevel(log.ErrorLevel)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetLevel(log.InfoLevel)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	m.Run(ctx)
+	if !strings.Contains(logged.String(), "level=error") || !strings.Contains(logged.String(), "failed to save store") {
+		t.Errorf("Expected the failed checks to be logged as errors, got %q", logged.String())
+	}
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink - A receiver of the events of a check.
+type Sink interface {
+	// Send delivers the events of a check, in the order they were emitted.
+	Send(events []Event) error
+}
+
+// SinkFunc - A Go callback used as a Sink.
+type SinkFunc func(events []Event) error
+
+// Send calls f with events.
+func (f SinkFunc) Send(events []Event) error {
+	return f(events)
+}
+
+// JSONLines - A Sink appending every event as a line of JSON to a file.
+type JSONLines struct {
+	// FileName of the file, created if missing.
+	FileName string
+
+	mutex sync.Mutex
+}
+
+// Send appends events to the file.
+func (s *JSONLines) Send(events []Event) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	file, err := os.OpenFile(s.FileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+// Webhook - A Sink posting the events of a check as a JSON object {"events": [...]}.
+type Webhook struct {
+	// URL the events are posted to.
+	URL string
+	// Header is added to the requests, eg. an Authorization header.
+	Header http.Header
+	// Client of the requests, defaults to a client with a 10 seconds timeout.
+	Client *http.Client
+}
+
+// webhookClient is the client of webhooks without one.
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Send posts events to the URL. A response status other than 2xx is an error.
+func (s Webhook) Send(events []Event) error {
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{events})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range s.Header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = webhookClient
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status code %d", s.URL, resp.StatusCode)
+	}
+	return nil
+}
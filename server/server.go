@@ -0,0 +1,250 @@
+// Package server serves parsed playlists over HTTP.
+//
+// A Handler loads its sources into memory, refreshes them on an interval and serves filtered
+// playlists generated from the in-memory collection:
+//
+//	/playlist.m3u?category=news&country=NP&status=GOOD
+//	/playlist.json?q=title ~ "news"&sort=title
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config - Configuration of a Handler.
+type Config struct {
+	// Sources are URLs, file paths or raw playlists merged with M3uParser.ParseSources.
+	Sources []string
+	// Refresh is the interval Run reloads the sources at. Zero disables refreshing.
+	Refresh time.Duration
+	// CheckLive checks the streams on every load so status filters can be used.
+	CheckLive bool
+	// Timeout and UserAgent of the liveness checks, see M3uParser.
+	Timeout   int
+	UserAgent string
+	// Prepare is applied to every load before it is served, eg. to dedupe or filter out streams.
+	Prepare func(m3uparser.Collection) m3uparser.Collection
+}
+
+// formats are the playlist formats served as /playlist.<format> with their content type.
+var formats = map[string]string{
+	"m3u":  "audio/x-mpegurl",
+	"m3u8": "audio/x-mpegurl",
+	"json": "application/json",
+	"pls":  "audio/x-scpls",
+	"xspf": "application/xspf+xml",
+	"asx":  "video/x-ms-asf",
+	"csv":  "text/csv; charset=utf-8",
+	"tsv":  "text/tab-separated-values; charset=utf-8",
+}
+
+// filterAliases maps query parameters to key paths.
+var filterAliases = map[string]string{
+	"country":  "country.code",
+	"tvg-id":   "tvg.id",
+	"tvg-name": "tvg.name",
+	"group":    "category",
+}
+
+// channelKeys are the keys of parsed channels, a filter parameter must start with one of them or
+// with a key of the served channels.
+var channelKeys = map[string]bool{
+	"title": true, "url": true, "category": true, "logo": true, "language": true, "tvg": true,
+	"country": true, "status": true, "latency": true, "duration": true, "source": true,
+	"alternatives": true, "attributes": true,
+}
+
+// filterKey returns the key path filtered by the query parameter param or an error if it
+// doesn't address a channel key, eg. a cache buster like _=123.
+func filterKey(collection m3uparser.Collection, param string) (string, error) {
+	if alias, ok := filterAliases[param]; ok {
+		return alias, nil
+	}
+	key := param
+	if i := strings.IndexAny(param, `.[\`); i >= 0 {
+		key = param[:i]
+	} else if i := strings.IndexByte(param, '-'); i > 0 && !hasKey(collection, param) {
+		// a "key-nested" path, see m3uparser.Path
+		key = param[:i]
+	}
+	if !channelKeys[key] && !hasKey(collection, key) {
+		return "", fmt.Errorf("unknown query parameter %q", param)
+	}
+	return param, nil
+}
+
+// hasKey reports whether a channel of collection has key.
+func hasKey(collection m3uparser.Collection, key string) bool {
+	for _, channel := range collection.Channels() {
+		if _, ok := channel[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler - An http.Handler serving the playlist of its sources.
+type Handler struct {
+	config Config
+
+	mutex       sync.RWMutex
+	collection  m3uparser.Collection
+	fingerprint string
+	modified    time.Time
+}
+
+// New returns a handler of config. Call Refresh or Run to load the sources.
+func New(config Config) *Handler {
+	return &Handler{config: config}
+}
+
+// Collection returns the collection currently served.
+func (h *Handler) Collection() m3uparser.Collection {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.collection
+}
+
+// Modified returns the time the served collection last changed.
+func (h *Handler) Modified() time.Time {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.modified
+}
+
+// Refresh loads the sources. Sources that fail are logged and skipped, the served collection is
+// kept if no source could be loaded.
+func (h *Handler) Refresh() error {
+	parser := m3uparser.M3uParser{Timeout: h.config.Timeout, UserAgent: h.config.UserAgent}
+	errs := parser.ParseSources(h.config.Sources, h.config.CheckLive, false)
+	if len(errs) == len(h.config.Sources) && len(errs) > 0 {
+		return fmt.Errorf("no source could be loaded: %v", errs[0])
+	}
+	collection := parser.Collection()
+	if h.config.Prepare != nil {
+		collection = h.config.Prepare(collection)
+	}
+	if err := collection.Err(); err != nil {
+		return err
+	}
+	var content bytes.Buffer
+	if err := collection.WriteJSON(&content); err != nil {
+		return err
+	}
+	fingerprint := hash(content.Bytes())
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.collection = collection
+	if fingerprint != h.fingerprint {
+		h.fingerprint = fingerprint
+		h.modified = time.Now().UTC().Truncate(time.Second)
+	}
+	log.Infof("Serving %d streams", collection.Len())
+	return nil
+}
+
+// Run refreshes the sources every Refresh interval until ctx is done. Failed refreshes are logged.
+func (h *Handler) Run(ctx context.Context) {
+	if h.config.Refresh <= 0 {
+		return
+	}
+	ticker := time.NewTicker(h.config.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.Refresh(); err != nil {
+				log.Warnf("Failed to refresh sources: %v", err)
+			}
+		}
+	}
+}
+
+func hash(content []byte) string {
+	sum := sha1.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// query applies the query parameters to collection:
+//   - q: a query expression, see m3uparser.Query.
+//   - sort: sort keys, see m3uparser.ParseSortKeys.
+//   - any other parameter filters on its key path, eg. category=news or attributes.tvg-chno=7.
+//     Values match case-insensitively as substring, repeated parameters match any value.
+//     country, group, tvg-id and tvg-name are aliases of country.code, category, tvg.id and tvg.name.
+//     A key path must start with a key of the channels, other parameters are an error.
+func query(collection m3uparser.Collection, r *http.Request) (m3uparser.Collection, error) {
+	params := r.URL.Query()
+	filtered := collection
+	for param, values := range params {
+		if param == "q" || param == "sort" {
+			continue
+		}
+		key, err := filterKey(collection, param)
+		if err != nil {
+			return collection, err
+		}
+		filtered = filtered.Filter(key, values, true, m3uparser.FilterOptions{})
+	}
+	collection = filtered
+	if expr := params.Get("q"); expr != "" {
+		collection = collection.Where(expr)
+	}
+	if spec := params.Get("sort"); spec != "" {
+		keys, err := m3uparser.ParseSortKeys(spec)
+		if err != nil {
+			return collection, err
+		}
+		collection = collection.Sort(keys...)
+	}
+	return collection, collection.Err()
+}
+
+// ServeHTTP serves /playlist.m3u, /playlist.json and the other formats of the filtered collection
+// with ETag and Last-Modified headers so clients can revalidate.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Base(r.URL.Path)
+	format := strings.TrimPrefix(path.Ext(name), ".")
+	contentType, ok := formats[format]
+	if !ok || strings.TrimSuffix(name, path.Ext(name)) != "playlist" {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.mutex.RLock()
+	collection, modified := h.collection, h.modified
+	h.mutex.RUnlock()
+
+	collection, err := query(collection, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var body bytes.Buffer
+	if err := collection.WriteFormat(&body, format); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hash(body.Bytes())+`"`)
+	http.ServeContent(w, r, name, modified, bytes.NewReader(body.Bytes()))
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const playlist = `#EXTM3U
+#EXTINF:-1 tvg-id="KantipurTV.np" tvg-country="NP" group-title="News",Kantipur TV
+http://example.com/kantipur.m3u8
+#EXTINF:-1 tvg-id="BBCNews.uk" tvg-country="UK" group-title="News",BBC News
+http://example.com/bbc.m3u8
+#EXTINF:-1 tvg-id="Sports1.np" tvg-country="NP" group-title="Sports",Sports 1
+http://example.com/sports.m3u8
+`
+
+func newHandler(t *testing.T, content string) (*Handler, string) {
+	file := filepath.Join(t.TempDir(), "playlist.m3u")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	handler := New(Config{Sources: []string{file}})
+	if err := handler.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return handler, file
+}
+
+func get(handler http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServePlaylist(t *testing.T) {
+	handler, _ := newHandler(t, playlist)
+	tests := []struct {
+		target string
+		status int
+		titles []string
+	}{
+		{"/playlist.m3u", http.StatusOK, []string{"Kantipur TV", "BBC News", "Sports 1"}},
+		{"/playlist.m3u?category=news&country=NP", http.StatusOK, []string{"Kantipur TV"}},
+		{"/playlist.m3u?country=np&country=uk&sort=-title", http.StatusOK, []string{"Sports 1", "Kantipur TV", "BBC News"}},
+		{"/playlist.json?q=" + strings.ReplaceAll(`category == "Sports"`, " ", "%20"), http.StatusOK, []string{"Sports 1"}},
+		{"/playlist.m3u?sort=title..", http.StatusBadRequest, nil},
+		{"/playlist.m3u?q=(", http.StatusBadRequest, nil},
+		{"/playlist.m3u?_=123", http.StatusBadRequest, nil},
+		{"/playlist.m3u?category=news&utm_source=mail", http.StatusBadRequest, nil},
+		{"/playlist.m3u?status=GOOD", http.StatusOK, []string{}},
+		{"/playlist.m3u?tvg.id=kantipur", http.StatusOK, []string{"Kantipur TV"}},
+		{"/other.m3u", http.StatusNotFound, nil},
+		{"/playlist.txt", http.StatusNotFound, nil},
+	}
+	for _, test := range tests {
+		response := get(handler, test.target, nil)
+		if response.Code != test.status {
+			t.Errorf("%s: status %d, want %d", test.target, response.Code, test.status)
+			continue
+		}
+		if test.titles == nil {
+			continue
+		}
+		body := response.Body.String()
+		last := -1
+		for _, title := range test.titles {
+			index := strings.Index(body, title)
+			if index <= last {
+				t.Errorf("%s: %q missing or out of order in\n%s", test.target, title, body)
+			}
+			last = index
+		}
+		if count := strings.Count(body, "http://example.com/"); count != len(test.titles) {
+			t.Errorf("%s: %d streams, want %d", test.target, count, len(test.titles))
+		}
+	}
+	if contentType := get(handler, "/playlist.json", nil).Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("content type %q", contentType)
+	}
+}
+
+func TestServeRevalidation(t *testing.T) {
+	handler, file := newHandler(t, playlist)
+	response := get(handler, "/playlist.m3u?country=NP", nil)
+	etag, modified := response.Header().Get("ETag"), response.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("missing ETag %q or Last-Modified %q", etag, modified)
+	}
+	if code := get(handler, "/playlist.m3u?country=NP", http.Header{"If-None-Match": {etag}}).Code; code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status %d, want 304", code)
+	}
+	if other := get(handler, "/playlist.m3u?country=UK", nil).Header().Get("ETag"); other == etag {
+		t.Error("different filters share an ETag")
+	}
+
+	// an unchanged refresh keeps Last-Modified
+	before := handler.Modified()
+	if err := handler.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if !handler.Modified().Equal(before) {
+		t.Error("unchanged refresh changed Last-Modified")
+	}
+
+	// a failed refresh keeps serving the previous collection
+	os.Remove(file)
+	if err := handler.Refresh(); err == nil {
+		t.Error("refresh of a missing source succeeded")
+	}
+	if handler.Collection().Len() != 3 {
+		t.Errorf("served %d streams after failed refresh, want 3", handler.Collection().Len())
+	}
+
+	// a changed source is served with a new ETag
+	ioutil.WriteFile(file, []byte(strings.Replace(playlist, "Kantipur TV\n", "Kantipur HD\n", 1)), 0644)
+	if err := handler.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	response = get(handler, "/playlist.m3u?country=NP", http.Header{"If-None-Match": {etag}})
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Kantipur HD") {
+		t.Errorf("changed source: status %d, body\n%s", response.Code, response.Body.String())
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// lockTimeout is how long Save waits for the lock of the file, and staleLockAge the age after
+// which a lock is taken over. The holder of a lock refreshes it every staleLockAge/4, so only the
+// lock of a crashed or hung process gets stale.
+var (
+	lockTimeout  = 10 * time.Second
+	staleLockAge = time.Minute
+)
+
+// lockCount makes the tokens of the locks of a process unique.
+var lockCount int64
+
+// fileLock - A lock file held by Save, containing the token of its holder.
+type fileLock struct {
+	name  string
+	token []byte
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// lock creates the lock file of the store file, taking over a stale one, and refreshes it until
+// unlock.
+func lock(fileName string) (*fileLock, error) {
+	l := &fileLock{
+		name:  fileName + ".lock",
+		token: []byte(fmt.Sprintf("%d-%d-%d", os.Getpid(), time.Now().UnixNano(), atomic.AddInt64(&lockCount, 1))),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lockFile, err := os.OpenFile(l.name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = lockFile.Write(l.token)
+			if closeErr := lockFile.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(l.name)
+				return nil, err
+			}
+			go l.refresh()
+			return l, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(l.name); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			l.takeOver()
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("store %s is locked by %s", fileName, l.name)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// takeOver removes a stale lock. The lock is renamed first so only one of the processes waiting
+// for it removes it; a lock renamed after another process took it over is fresh and given back.
+func (l *fileLock) takeOver() {
+	staleName := l.name + "." + string(l.token) + ".stale"
+	if err := os.Rename(l.name, staleName); err != nil {
+		return
+	}
+	if info, err := os.Stat(staleName); err == nil && time.Since(info.ModTime()) <= staleLockAge {
+		os.Link(staleName, l.name)
+	}
+	os.Remove(staleName)
+}
+
+// refresh updates the modification time of the lock file until unlock so it doesn't get stale.
+func (l *fileLock) refresh() {
+	defer close(l.done)
+	ticker := time.NewTicker(staleLockAge / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.name, now, now)
+		}
+	}
+}
+
+// held reports whether the lock file is still the one created by lock.
+func (l *fileLock) held() bool {
+	content, err := ioutil.ReadFile(l.name)
+	return err == nil && bytes.Equal(content, l.token)
+}
+
+// unlock stops refreshing the lock file and removes it if it is still held.
+func (l *fileLock) unlock() {
+	close(l.stop)
+	<-l.done
+	if l.held() {
+		os.Remove(l.name)
+	}
+}
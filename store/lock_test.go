@@ -0,0 +1,77 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockRefresh(t *testing.T) {
+	defer func(timeout, age time.Duration) { lockTimeout, staleLockAge = timeout, age }(lockTimeout, staleLockAge)
+	lockTimeout, staleLockAge = 600*time.Millisecond, 200*time.Millisecond
+	fileName := filepath.Join(t.TempDir(), "store.json")
+
+	// a lock held longer than staleLockAge is refreshed and not taken over
+	held, err := lock(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * staleLockAge)
+	if other, err := lock(fileName); err == nil {
+		other.unlock()
+		t.Fatal("Expected a held lock to time out")
+	}
+	if !held.held() {
+		t.Error("The lock was taken over while held")
+	}
+	held.unlock()
+	if _, err := os.Stat(fileName + ".lock"); !os.IsNotExist(err) {
+		t.Error("unlock left the lock file")
+	}
+}
+
+func TestLockTakeOver(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "store.json")
+	lockName := fileName + ".lock"
+	if err := ioutil.WriteFile(lockName, []byte("crashed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * staleLockAge)
+	os.Chtimes(lockName, stale, stale)
+
+	// the processes waiting for a stale lock hold it one at a time
+	var holders, maxHolders int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := lock(fileName)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				max := atomic.LoadInt32(&maxHolders)
+				if n <= max || atomic.CompareAndSwapInt32(&maxHolders, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			l.unlock()
+		}()
+	}
+	wg.Wait()
+	if maxHolders != 1 {
+		t.Errorf("%d processes held the lock at once", maxHolders)
+	}
+	if matches, _ := filepath.Glob(lockName + "*"); len(matches) != 0 {
+		t.Errorf("Lock files left %v", matches)
+	}
+}
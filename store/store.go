@@ -0,0 +1,377 @@
+// Package store records channels across parses in a local JSON file: when each channel was
+// first and last seen and the history of its liveness checks, so a channel that has been down
+// for a week can be told apart from one that failed a single check.
+//
+// Channels are identified by their URL. Uptimes are the percentage of GOOD checks and are added
+// to channels by Annotate, so they can be used in filters:
+//
+//	st.Record(parser.Collection(), time.Now())
+//	reliable := st.Annotate(parser.Collection(), time.Now().AddDate(0, 0, -7)).Where("uptime >= 90")
+//
+// Several stores, eg. of different processes, can share a file: Save locks the file, re-reads it
+// and merges the changes made since the last Save, so no process overwrites the history of another.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+// Version of the store file format.
+const Version = 1
+
+// DefaultMaxChecks is the number of checks kept per channel if Config.MaxChecks is zero.
+const DefaultMaxChecks = 1000
+
+// Config - Configuration of a Store.
+type Config struct {
+	// MaxChecks is the number of most recent checks kept per channel, defaults to DefaultMaxChecks.
+	MaxChecks int
+}
+
+// Check - A liveness check of a channel.
+type Check struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	// Latency in milliseconds of a GOOD check.
+	Latency int64 `json:"latency,omitempty"`
+}
+
+// Record - A channel recorded by a Store.
+type Record struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title,omitempty"`
+	TvgID     string    `json:"tvg_id,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Checks are in chronological order.
+	Checks []Check `json:"checks,omitempty"`
+}
+
+// Uptime returns the percentage of GOOD checks since a time, of all checks for a zero since.
+// It returns false if the channel wasn't checked since then.
+func (r Record) Uptime(since time.Time) (float64, bool) {
+	var good, total int
+	for _, check := range r.Checks {
+		if check.Time.Before(since) {
+			continue
+		}
+		total++
+		if check.Status == "GOOD" {
+			good++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(good) * 100 / float64(total), true
+}
+
+// DownSince returns the time of the first of the BAD checks ending the history. It returns
+// false if the last check is GOOD or the channel was never checked.
+func (r Record) DownSince() (time.Time, bool) {
+	var since time.Time
+	for i := len(r.Checks) - 1; i >= 0 && r.Checks[i].Status == "BAD"; i-- {
+		since = r.Checks[i].Time
+	}
+	return since, !since.IsZero()
+}
+
+// file - The content of a store file.
+type file struct {
+	Version  int      `json:"version"`
+	Channels []Record `json:"channels"`
+}
+
+// records - Records in the order the channels were first seen, indexed by url.
+type records struct {
+	list  []*Record
+	index map[string]*Record
+}
+
+// readRecords reads the records of fileName, none if it doesn't exist.
+func readRecords(fileName string) (records, error) {
+	result := records{index: make(map[string]*Record)}
+	content, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	var stored file
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return result, fmt.Errorf("store %s: %v", fileName, err)
+	}
+	if stored.Version != Version {
+		return result, fmt.Errorf("store %s: unsupported version %d, want %d", fileName, stored.Version, Version)
+	}
+	for i := range stored.Channels {
+		record := &stored.Channels[i]
+		if _, ok := result.index[record.URL]; ok || record.URL == "" {
+			continue
+		}
+		result.list = append(result.list, record)
+		result.index[record.URL] = record
+	}
+	return result, nil
+}
+
+// change - A change of the records: a channel seen, with a check if it was checked, or a prune.
+type change struct {
+	url, title, tvgID string
+	at                time.Time
+	check             *Check
+	pruneBefore       time.Time
+}
+
+// apply applies c to the records, keeping at most maxChecks checks per channel. It returns the
+// number of records removed by a prune.
+func (r *records) apply(c change, maxChecks int) int {
+	if !c.pruneBefore.IsZero() {
+		kept := r.list[:0]
+		for _, record := range r.list {
+			if record.LastSeen.Before(c.pruneBefore) {
+				delete(r.index, record.URL)
+				continue
+			}
+			kept = append(kept, record)
+		}
+		removed := len(r.list) - len(kept)
+		r.list = kept
+		return removed
+	}
+	record, ok := r.index[c.url]
+	if !ok {
+		record = &Record{URL: c.url, FirstSeen: c.at}
+		r.list = append(r.list, record)
+		r.index[c.url] = record
+	}
+	if c.at.Before(record.FirstSeen) {
+		record.FirstSeen = c.at
+	}
+	if c.at.After(record.LastSeen) {
+		record.LastSeen = c.at
+	}
+	if c.title != "" {
+		record.Title = c.title
+	}
+	if c.tvgID != "" {
+		record.TvgID = c.tvgID
+	}
+	if c.check == nil {
+		return 0
+	}
+	// checks merged from another store may be older than the last one
+	i := sort.Search(len(record.Checks), func(i int) bool { return record.Checks[i].Time.After(c.at) })
+	record.Checks = append(record.Checks, Check{})
+	copy(record.Checks[i+1:], record.Checks[i:])
+	record.Checks[i] = *c.check
+	if extra := len(record.Checks) - maxChecks; extra > 0 {
+		record.Checks = append([]Check(nil), record.Checks[extra:]...)
+	}
+	return 0
+}
+
+// Store - Channels recorded across parses, persisted in a JSON file. It is safe for concurrent use.
+type Store struct {
+	fileName string
+	config   Config
+
+	mutex   sync.RWMutex
+	records records
+	// changes since the last Save, merged into the file by Save
+	changes []change
+}
+
+// Open returns the store of fileName. A missing file is created by the first Save.
+func Open(fileName string, config Config) (*Store, error) {
+	if config.MaxChecks <= 0 {
+		config.MaxChecks = DefaultMaxChecks
+	}
+	loaded, err := readRecords(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{fileName: fileName, config: config, records: loaded}, nil
+}
+
+// channelURL returns the url identifying channel in the store.
+func channelURL(channel m3uparser.Channel) string {
+	streamURL, _ := channel["url"].(string)
+	return strings.TrimSpace(streamURL)
+}
+
+// Record records the channels of collection as seen at a time and adds the status of checked
+// channels to their history. A URL listed more than once is recorded once, with its first
+// channel. Call Save to persist the changes.
+//
+// Parameters:
+//   - collection: The channels parsed, with a status if they were checked.
+//   - at: Time of the parse.
+func (s *Store) Record(collection m3uparser.Collection, at time.Time) error {
+	if err := collection.Err(); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	seen := make(map[string]bool)
+	for _, channel := range collection.Channels() {
+		c := change{url: channelURL(channel), at: at}
+		if c.url == "" || seen[c.url] {
+			continue
+		}
+		seen[c.url] = true
+		c.title, _ = channel["title"].(string)
+		if tvg, ok := channel["tvg"].(map[string]string); ok {
+			c.tvgID = tvg["id"]
+		}
+		if status, _ := channel["status"].(string); status == "GOOD" || status == "BAD" {
+			c.check = &Check{Time: at, Status: status}
+			if latency, ok := channel["latency"].(int64); ok && status == "GOOD" {
+				c.check.Latency = latency
+			}
+		}
+		s.records.apply(c, s.config.MaxChecks)
+		s.changes = append(s.changes, c)
+	}
+	return nil
+}
+
+// copyRecord returns a copy of record not sharing its checks.
+func copyRecord(record *Record) Record {
+	copied := *record
+	copied.Checks = append([]Check(nil), record.Checks...)
+	return copied
+}
+
+// Get returns the record of the channel with streamURL.
+func (s *Store) Get(streamURL string) (Record, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	record, ok := s.records.index[strings.TrimSpace(streamURL)]
+	if !ok {
+		return Record{}, false
+	}
+	return copyRecord(record), true
+}
+
+// Records returns all records in the order the channels were first seen.
+func (s *Store) Records() []Record {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	records := make([]Record, len(s.records.list))
+	for i, record := range s.records.list {
+		records[i] = copyRecord(record)
+	}
+	return records
+}
+
+// Prune removes the channels last seen before a time and returns the number removed.
+func (s *Store) Prune(before time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c := change{pruneBefore: before}
+	s.changes = append(s.changes, c)
+	return s.records.apply(c, s.config.MaxChecks)
+}
+
+// Annotate returns the channels of collection with their history: "first_seen" and "last_seen"
+// times, "uptime", the percentage of GOOD checks since a time (all checks for a zero since), and
+// "down_since" if the last checks are BAD. Times are RFC 3339 strings. Channels not in the store
+// are unchanged and "uptime" is only set on checked channels, so eg. "uptime >= 90" drops the others.
+func (s *Store) Annotate(collection m3uparser.Collection, since time.Time) m3uparser.Collection {
+	if collection.Err() != nil {
+		return collection
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	channels := collection.Channels()
+	for i, channel := range channels {
+		record, ok := s.records.index[channelURL(channel)]
+		if !ok {
+			continue
+		}
+		annotated := make(m3uparser.Channel, len(channel)+4)
+		for key, value := range channel {
+			annotated[key] = value
+		}
+		annotated["first_seen"] = record.FirstSeen.Format(time.RFC3339)
+		annotated["last_seen"] = record.LastSeen.Format(time.RFC3339)
+		if uptime, ok := record.Uptime(since); ok {
+			annotated["uptime"] = uptime
+		}
+		if down, ok := record.DownSince(); ok {
+			annotated["down_since"] = down.Format(time.RFC3339)
+		}
+		channels[i] = annotated
+	}
+	return m3uparser.NewCollection(channels)
+}
+
+// Save merges the changes since the last Save into the file: it locks the file, re-reads it,
+// applies the changes and replaces it atomically, so a failed save keeps the previous content.
+// The store then has the merged records, including those saved by other stores of the file.
+func (s *Store) Save() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	fileLock, err := lock(s.fileName)
+	if err != nil {
+		return err
+	}
+	defer fileLock.unlock()
+	merged, err := readRecords(s.fileName)
+	if err != nil {
+		return err
+	}
+	for _, c := range s.changes {
+		merged.apply(c, s.config.MaxChecks)
+	}
+	stored := file{Version: Version, Channels: make([]Record, len(merged.list))}
+	for i, record := range merged.list {
+		stored.Channels[i] = *record
+	}
+	content, err := json.MarshalIndent(stored, "", "    ")
+	if err != nil {
+		return err
+	}
+	if !fileLock.held() {
+		return fmt.Errorf("store %s: lost the lock %s", s.fileName, fileLock.name)
+	}
+	if err := writeFile(s.fileName, content); err != nil {
+		return err
+	}
+	s.records = merged
+	s.changes = nil
+	return nil
+}
+
+// writeFile replaces fileName with content atomically.
+func writeFile(fileName string, content []byte) error {
+	temp, err := ioutil.TempFile(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if err := temp.Chmod(0644); err != nil {
+		temp.Close()
+		return err
+	}
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), fileName)
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+func collection(statuses ...string) m3uparser.Collection {
+	urls := []string{"http://example.com/news", "http://example.com/sports", "http://example.com/movies"}
+	var channels []m3uparser.Channel
+	for i, status := range statuses {
+		channel := m3uparser.Channel{"title": "Channel", "url": urls[i]}
+		if status != "" {
+			channel["status"] = status
+			channel["latency"] = int64(100)
+		}
+		channels = append(channels, channel)
+	}
+	return m3uparser.NewCollection(channels)
+}
+
+func TestStore(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "store.json")
+	st, err := Open(fileName, Config{MaxChecks: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	runs := []m3uparser.Collection{
+		collection("GOOD", "GOOD"),
+		collection("GOOD", "BAD"),
+		collection("BAD", "BAD", ""),
+		collection("GOOD", "BAD", "GOOD"),
+	}
+	for i, run := range runs {
+		if err := st.Record(run, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the history survives reopening
+	st, err = Open(fileName, Config{MaxChecks: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := st.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	news, sports, movies := records[0], records[1], records[2]
+	if !news.FirstSeen.Equal(start) || !news.LastSeen.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("News seen %v to %v", news.FirstSeen, news.LastSeen)
+	}
+	if len(news.Checks) != 3 || news.Checks[0].Status != "GOOD" || news.Checks[1].Status != "BAD" {
+		t.Errorf("Expected the 3 most recent checks, got %v", news.Checks)
+	}
+	if uptime, ok := news.Uptime(time.Time{}); !ok || int(uptime) != 66 {
+		t.Errorf("News uptime %v %v, want 66%%", uptime, ok)
+	}
+	if uptime, ok := news.Uptime(start.Add(3 * time.Hour)); !ok || uptime != 100 {
+		t.Errorf("News recent uptime %v %v, want 100%%", uptime, ok)
+	}
+	if _, ok := news.DownSince(); ok {
+		t.Error("News is up")
+	}
+	if since, ok := sports.DownSince(); !ok || !since.Equal(start.Add(time.Hour)) {
+		t.Errorf("Sports down since %v %v", since, ok)
+	}
+	if !movies.FirstSeen.Equal(start.Add(2*time.Hour)) || len(movies.Checks) != 1 {
+		t.Errorf("Movies first seen %v with checks %v", movies.FirstSeen, movies.Checks)
+	}
+
+	annotated := st.Annotate(runs[3], time.Time{})
+	if annotated.At(1)["down_since"] != "2021-05-01T01:00:00Z" || annotated.At(1)["uptime"] != float64(0) {
+		t.Errorf("Sports annotated %v", annotated.At(1))
+	}
+	if _, ok := runs[3].At(0)["uptime"]; ok {
+		t.Error("Annotate changed the channels of the collection")
+	}
+	reliable := annotated.Where("uptime >= 50")
+	if err := reliable.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if reliable.Len() != 2 || reliable.At(0)["url"] != news.URL || reliable.At(1)["url"] != movies.URL {
+		t.Errorf("Expected news and movies to have an uptime of 50%% or more, got %v", reliable.Channels())
+	}
+
+	if removed := st.Prune(start.Add(3 * time.Hour)); removed != 0 {
+		t.Errorf("Pruned %d records seen in the last run", removed)
+	}
+	st.Record(collection("GOOD"), start.Add(4*time.Hour))
+	if removed := st.Prune(start.Add(4 * time.Hour)); removed != 2 {
+		t.Errorf("Pruned %d records, want 2", removed)
+	}
+	if _, ok := st.Get("http://example.com/sports"); ok {
+		t.Error("Pruned record is still in the store")
+	}
+}
+
+func TestStoreRecordDuplicates(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "store.json"), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	duplicated := m3uparser.NewCollection([]m3uparser.Channel{
+		{"title": "News", "url": "http://example.com/news", "status": "GOOD"},
+		{"title": "News Mirror", "url": " http://example.com/news", "status": "GOOD"},
+	})
+	st.Record(duplicated, start)
+	st.Record(collection("BAD"), start.Add(time.Hour))
+	news, _ := st.Get("http://example.com/news")
+	if len(news.Checks) != 2 || news.Title != "Channel" {
+		t.Errorf("Expected a check per Record, got %v", news.Checks)
+	}
+	if uptime, _ := news.Uptime(time.Time{}); uptime != 50 {
+		t.Errorf("Uptime %v, want 50", uptime)
+	}
+}
+
+func TestStoreSharedFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "store.json")
+	start := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	first, err := Open(fileName, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Open(fileName, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both stores were opened before either saved, neither overwrites the checks of the other
+	first.Record(collection("GOOD"), start.Add(time.Hour))
+	second.Record(collection("BAD", "GOOD"), start)
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Save(); err != nil {
+		t.Fatal(err)
+	}
+	news, ok := second.Get("http://example.com/news")
+	if !ok || len(news.Checks) != 2 || news.Checks[0].Status != "BAD" || news.Checks[1].Status != "GOOD" {
+		t.Errorf("Merged news checks %v", news.Checks)
+	}
+	if !news.FirstSeen.Equal(start) || !news.LastSeen.Equal(start.Add(time.Hour)) {
+		t.Errorf("Merged news seen %v to %v", news.FirstSeen, news.LastSeen)
+	}
+
+	// concurrent saves of several stores keep every check
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		st, err := Open(fileName, Config{MaxChecks: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				st.Record(collection("GOOD"), start.Add(time.Duration(2+i*4+j)*time.Hour))
+				if err := st.Save(); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	reopened, err := Open(fileName, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if news, _ := reopened.Get("http://example.com/news"); len(news.Checks) != 22 {
+		t.Errorf("Expected 22 checks after concurrent saves, got %d", len(news.Checks))
+	}
+
+	// a lock left by a process is waited for, a stale one is removed
+	lockName := fileName + ".lock"
+	if err := ioutil.WriteFile(lockName, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * staleLockAge)
+	os.Chtimes(lockName, stale, stale)
+	if err := reopened.Save(); err != nil {
+		t.Errorf("Save with a stale lock: %v", err)
+	}
+	if _, err := os.Stat(lockName); !os.IsNotExist(err) {
+		t.Error("Save left the lock file")
+	}
+}
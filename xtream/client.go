@@ -0,0 +1,469 @@
+// Package xtream reads playlists from the player API of Xtream Codes servers and serves
+// collections to Xtream Codes apps, see Client and Server.
+package xtream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+	log "github.com/sirupsen/logrus"
+)
+
+// Value - A JSON value Xtream Codes servers send either as string, number or null.
+type Value string
+
+// UnmarshalJSON accepts strings, numbers, booleans and null.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case nil:
+		*v = ""
+	case string:
+		*v = Value(value)
+	case float64:
+		*v = Value(strconv.FormatFloat(value, 'f', -1, 64))
+	case bool:
+		*v = "0"
+		if value {
+			*v = "1"
+		}
+	default:
+		return fmt.Errorf("unexpected xtream value %s", data)
+	}
+	return nil
+}
+
+// Int returns the value as int, 0 if it isn't a number.
+func (v Value) Int() int {
+	n, _ := strconv.Atoi(strings.TrimSpace(string(v)))
+	return n
+}
+
+// UserInfo - The user_info of a login.
+type UserInfo struct {
+	Username             string   `json:"username"`
+	Password             string   `json:"password"`
+	Auth                 Value    `json:"auth"`
+	Status               string   `json:"status"`
+	ExpDate              Value    `json:"exp_date"`
+	IsTrial              Value    `json:"is_trial"`
+	ActiveCons           Value    `json:"active_cons"`
+	CreatedAt            Value    `json:"created_at"`
+	MaxConnections       Value    `json:"max_connections"`
+	AllowedOutputFormats []string `json:"allowed_output_formats"`
+}
+
+// ServerInfo - The server_info of a login.
+type ServerInfo struct {
+	URL            string `json:"url"`
+	Port           Value  `json:"port"`
+	HTTPSPort      Value  `json:"https_port"`
+	ServerProtocol string `json:"server_protocol"`
+	Timezone       string `json:"timezone"`
+	TimestampNow   Value  `json:"timestamp_now"`
+	TimeNow        string `json:"time_now"`
+}
+
+// Account - The response of player_api.php without action.
+type Account struct {
+	UserInfo   UserInfo   `json:"user_info"`
+	ServerInfo ServerInfo `json:"server_info"`
+}
+
+// Category - A live, VOD or series category.
+type Category struct {
+	CategoryID   Value  `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	ParentID     Value  `json:"parent_id"`
+}
+
+// Stream - A live or VOD stream of get_live_streams or get_vod_streams.
+type Stream struct {
+	Num                Value  `json:"num"`
+	Name               string `json:"name"`
+	StreamType         string `json:"stream_type"`
+	StreamID           Value  `json:"stream_id"`
+	StreamIcon         string `json:"stream_icon"`
+	EPGChannelID       string `json:"epg_channel_id"`
+	Added              Value  `json:"added"`
+	CategoryID         Value  `json:"category_id"`
+	CustomSID          string `json:"custom_sid"`
+	TVArchive          Value  `json:"tv_archive"`
+	TVArchiveDuration  Value  `json:"tv_archive_duration"`
+	DirectSource       string `json:"direct_source"`
+	ContainerExtension string `json:"container_extension"`
+	Rating             Value  `json:"rating"`
+}
+
+// Series - A series of get_series.
+type Series struct {
+	Num        Value  `json:"num"`
+	Name       string `json:"name"`
+	SeriesID   Value  `json:"series_id"`
+	Cover      string `json:"cover"`
+	Plot       string `json:"plot"`
+	Genre      string `json:"genre"`
+	CategoryID Value  `json:"category_id"`
+}
+
+// Episode - An episode of get_series_info.
+type Episode struct {
+	ID                 Value  `json:"id"`
+	EpisodeNum         Value  `json:"episode_num"`
+	Title              string `json:"title"`
+	ContainerExtension string `json:"container_extension"`
+	Season             Value  `json:"season"`
+	Info               struct {
+		DurationSecs Value  `json:"duration_secs"`
+		MovieImage   string `json:"movie_image"`
+	} `json:"info"`
+}
+
+// Client - A client of the player_api.php of an Xtream Codes server.
+type Client struct {
+	// Server is the base URL of the server, eg. "http://example.com:8080".
+	Server   string
+	Username string
+	Password string
+	// HTTPClient is used for requests, defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+	// UserAgent is sent with requests if set.
+	UserAgent string
+}
+
+// NewClient returns a client of the server for the user.
+func NewClient(server, username, password string) *Client {
+	return &Client{
+		Server:     strings.TrimRight(server, "/"),
+		Username:   username,
+		Password:   password,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// get calls player_api.php with action and params and decodes the JSON response into result.
+func (c *Client) get(action string, params url.Values, result interface{}) error {
+	query := url.Values{"username": {c.Username}, "password": {c.Password}}
+	if action != "" {
+		query.Set("action", action)
+	}
+	for key, values := range params {
+		query[key] = values
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(c.Server, "/")+"/player_api.php?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("xtream %s: unexpected status code %d", actionName(action), resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("xtream %s: invalid response: %v", actionName(action), err)
+	}
+	return nil
+}
+
+func actionName(action string) string {
+	if action == "" {
+		return "login"
+	}
+	return action
+}
+
+// Login returns the account of the user. It returns an error if the login is not authorized.
+func (c *Client) Login() (*Account, error) {
+	var account Account
+	if err := c.get("", nil, &account); err != nil {
+		return nil, err
+	}
+	if account.UserInfo.Auth.Int() != 1 {
+		return nil, fmt.Errorf("xtream login: user %s is not authorized", c.Username)
+	}
+	return &account, nil
+}
+
+// LiveCategories returns the live categories.
+func (c *Client) LiveCategories() ([]Category, error) {
+	var categories []Category
+	err := c.get("get_live_categories", nil, &categories)
+	return categories, err
+}
+
+// LiveStreams returns the live streams of the category, all live streams for an empty categoryID.
+func (c *Client) LiveStreams(categoryID string) ([]Stream, error) {
+	var streams []Stream
+	err := c.get("get_live_streams", categoryParams(categoryID), &streams)
+	return streams, err
+}
+
+// VODCategories returns the VOD categories.
+func (c *Client) VODCategories() ([]Category, error) {
+	var categories []Category
+	err := c.get("get_vod_categories", nil, &categories)
+	return categories, err
+}
+
+// VODStreams returns the VOD streams of the category, all VOD streams for an empty categoryID.
+func (c *Client) VODStreams(categoryID string) ([]Stream, error) {
+	var streams []Stream
+	err := c.get("get_vod_streams", categoryParams(categoryID), &streams)
+	return streams, err
+}
+
+// SeriesCategories returns the series categories.
+func (c *Client) SeriesCategories() ([]Category, error) {
+	var categories []Category
+	err := c.get("get_series_categories", nil, &categories)
+	return categories, err
+}
+
+// Series returns the series of the category, all series for an empty categoryID.
+func (c *Client) Series(categoryID string) ([]Series, error) {
+	var series []Series
+	err := c.get("get_series", categoryParams(categoryID), &series)
+	return series, err
+}
+
+// Episodes returns the episodes of the series ordered by season and episode number. Panels send
+// the episodes as an object of seasons, an array of season arrays or an empty array.
+func (c *Client) Episodes(seriesID string) ([]Episode, error) {
+	var info struct {
+		Episodes json.RawMessage `json:"episodes"`
+	}
+	if err := c.get("get_series_info", url.Values{"series_id": {seriesID}}, &info); err != nil {
+		return nil, err
+	}
+	episodes, err := decodeEpisodes(info.Episodes)
+	if err != nil {
+		return nil, fmt.Errorf("xtream series %s: invalid episodes: %v", seriesID, err)
+	}
+	sort.SliceStable(episodes, func(i, j int) bool {
+		if episodes[i].Season.Int() != episodes[j].Season.Int() {
+			return episodes[i].Season.Int() < episodes[j].Season.Int()
+		}
+		return episodes[i].EpisodeNum.Int() < episodes[j].EpisodeNum.Int()
+	})
+	return episodes, nil
+}
+
+// decodeEpisodes decodes the episodes of get_series_info: an object of season numbers to
+// episodes, an array of season arrays numbered from 1, an array of episodes, empty or null.
+// Episodes without a season get the one they are listed under.
+func decodeEpisodes(raw json.RawMessage) ([]Episode, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	seasons := make(map[string]json.RawMessage)
+	if raw[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			if item = bytes.TrimSpace(item); len(item) > 0 && item[0] != '[' {
+				// a flat array of episodes
+				var episodes []Episode
+				err := json.Unmarshal(raw, &episodes)
+				return episodes, err
+			}
+			seasons[strconv.Itoa(i+1)] = item
+		}
+	} else if err := json.Unmarshal(raw, &seasons); err != nil {
+		return nil, err
+	}
+	var episodes []Episode
+	for season, content := range seasons {
+		var seasonEpisodes []Episode
+		if err := json.Unmarshal(content, &seasonEpisodes); err != nil {
+			return nil, err
+		}
+		for _, episode := range seasonEpisodes {
+			if episode.Season == "" {
+				episode.Season = Value(season)
+			}
+			episodes = append(episodes, episode)
+		}
+	}
+	return episodes, nil
+}
+
+func categoryParams(categoryID string) url.Values {
+	if categoryID == "" {
+		return nil
+	}
+	return url.Values{"category_id": {categoryID}}
+}
+
+// StreamURL returns the URL of a stream following the Xtream Codes scheme
+// {server}/{kind}/{username}/{password}/{id}.{extension}, kind is live, movie or series.
+func (c *Client) StreamURL(kind, id, extension string) string {
+	streamURL := fmt.Sprintf("%s/%s/%s/%s/%s", strings.TrimRight(c.Server, "/"), kind,
+		url.PathEscape(c.Username), url.PathEscape(c.Password), url.PathEscape(id))
+	if extension != "" {
+		streamURL += "." + extension
+	}
+	return streamURL
+}
+
+// PlaylistOptions - Options for building a playlist.
+type PlaylistOptions struct {
+	// Output is the extension of live streams, "ts" or "m3u8". Defaults to "ts".
+	Output string
+	// VOD adds movies and Series adds the episodes of every series, which needs a request per series.
+	VOD    bool
+	Series bool
+}
+
+// categoryNames returns the names of categories by id.
+func categoryNames(categories []Category, err error) (map[string]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(categories))
+	for _, category := range categories {
+		names[string(category.CategoryID)] = category.CategoryName
+	}
+	return names, nil
+}
+
+// liveChannel returns the channel of a live stream. Streams with archive get catch-up attributes
+// of the xc mode, see m3uparser.CatchupURL.
+func (c *Client) liveChannel(stream Stream, category, output string) m3uparser.Channel {
+	tvg := map[string]string{"name": strings.TrimSpace(stream.Name)}
+	if id := strings.TrimSpace(stream.EPGChannelID); id != "" {
+		tvg["id"] = id
+	}
+	channel := m3uparser.Channel{
+		"title": strings.TrimSpace(stream.Name),
+		"url":   c.StreamURL("live", string(stream.StreamID), output),
+		"tvg":   tvg,
+	}
+	setChannelString(channel, "logo", stream.StreamIcon)
+	setChannelString(channel, "category", category)
+	attributes := map[string]string{"xc-stream-id": string(stream.StreamID)}
+	if stream.Num != "" {
+		attributes["tvg-chno"] = string(stream.Num)
+	}
+	if stream.TVArchive.Int() == 1 {
+		attributes["catchup"] = m3uparser.CatchupXC
+		if days := stream.TVArchiveDuration.Int(); days > 0 {
+			attributes["catchup-days"] = strconv.Itoa(days)
+		}
+	}
+	channel["attributes"] = attributes
+	return channel
+}
+
+func setChannelString(channel m3uparser.Channel, key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		channel[key] = value
+	}
+}
+
+// Playlist returns the live streams, and optionally movies and series episodes, as channels like
+// parsed from an M3U playlist: title, logo, category from the category name, tvg id from the
+// epg channel id and the stream URL. Load them with M3uParser.Load. Series whose episodes can't be
+// loaded are logged and skipped.
+func (c *Client) Playlist(opts PlaylistOptions) ([]m3uparser.Channel, error) {
+	output := opts.Output
+	if output == "" {
+		output = "ts"
+	}
+	log.Infof("Started loading xtream playlist of %s...", c.Server)
+	if _, err := c.Login(); err != nil {
+		return nil, err
+	}
+	categories, err := categoryNames(c.LiveCategories())
+	if err != nil {
+		return nil, err
+	}
+	streams, err := c.LiveStreams("")
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]m3uparser.Channel, 0, len(streams))
+	for _, stream := range streams {
+		channels = append(channels, c.liveChannel(stream, categories[string(stream.CategoryID)], output))
+	}
+
+	if opts.VOD {
+		categories, err := categoryNames(c.VODCategories())
+		if err != nil {
+			return nil, err
+		}
+		movies, err := c.VODStreams("")
+		if err != nil {
+			return nil, err
+		}
+		for _, movie := range movies {
+			channel := m3uparser.Channel{
+				"title": strings.TrimSpace(movie.Name),
+				"url":   c.StreamURL("movie", string(movie.StreamID), movie.ContainerExtension),
+			}
+			setChannelString(channel, "logo", movie.StreamIcon)
+			setChannelString(channel, "category", categories[string(movie.CategoryID)])
+			channels = append(channels, channel)
+		}
+	}
+
+	if opts.Series {
+		categories, err := categoryNames(c.SeriesCategories())
+		if err != nil {
+			return nil, err
+		}
+		series, err := c.Series("")
+		if err != nil {
+			return nil, err
+		}
+		for _, show := range series {
+			episodes, err := c.Episodes(string(show.SeriesID))
+			if err != nil {
+				// one broken series shouldn't lose the playlist
+				log.Warnf("Skipping series %s: %v", show.Name, err)
+				continue
+			}
+			for _, episode := range episodes {
+				title := episode.Title
+				if title == "" {
+					title = fmt.Sprintf("%s S%02dE%02d", show.Name, episode.Season.Int(), episode.EpisodeNum.Int())
+				}
+				channel := m3uparser.Channel{
+					"title": strings.TrimSpace(title),
+					"url":   c.StreamURL("series", string(episode.ID), episode.ContainerExtension),
+				}
+				setChannelString(channel, "logo", show.Cover)
+				setChannelString(channel, "category", categories[string(show.CategoryID)])
+				if seconds := episode.Info.DurationSecs.Int(); seconds > 0 {
+					channel["duration"] = float64(seconds)
+				}
+				channels = append(channels, channel)
+			}
+		}
+	}
+	log.Infof("Loaded %d xtream streams", len(channels))
+	return channels, nil
+}
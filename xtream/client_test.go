@@ -0,0 +1,128 @@
+package xtream
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+// newFakeServer returns a player_api.php server for user/pass with numbers sent both as strings and numbers.
+func newFakeServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"": `{"user_info": {"username": "user", "auth": 1, "status": "Active", "max_connections": "1"},
+			"server_info": {"url": "example.com", "port": "8080", "timezone": "UTC"}}`,
+		"get_live_categories": `[{"category_id": "1", "category_name": "News", "parent_id": 0}, {"category_id": 2, "category_name": "Sports"}]`,
+		"get_live_streams": `[
+			{"num": 1, "name": "Kantipur TV", "stream_type": "live", "stream_id": 101, "stream_icon": "http://example.com/k.png",
+			 "epg_channel_id": "KantipurTV.np", "category_id": "1", "tv_archive": 1, "tv_archive_duration": "3"},
+			{"num": "2", "name": "Sports 1", "stream_id": "102", "stream_icon": "", "epg_channel_id": null, "category_id": 2, "tv_archive": 0}]`,
+		"get_vod_categories":    `[{"category_id": "10", "category_name": "Movies"}]`,
+		"get_vod_streams":       `[{"num": 1, "name": "A Movie", "stream_type": "movie", "stream_id": 201, "category_id": "10", "container_extension": "mp4"}]`,
+		"get_series_categories": `[{"category_id": "20", "category_name": "Shows"}]`,
+		"get_series": `[{"num": 1, "name": "A Show", "series_id": 301, "cover": "http://example.com/s.png", "category_id": "20"},
+			{"num": 2, "name": "Empty", "series_id": 302, "category_id": "20"},
+			{"num": 3, "name": "Seasons", "series_id": 303, "category_id": "20"},
+			{"num": 4, "name": "Broken", "series_id": 304, "category_id": "20"}]`,
+		"get_series_info:301": `{"episodes": {"2": [{"id": "402", "episode_num": 1, "title": "", "container_extension": "mkv"}],
+			"1": [{"id": "401", "episode_num": "1", "title": "Pilot", "container_extension": "mkv", "info": {"duration_secs": 1800}}]}}`,
+		"get_series_info:302": `{"seasons": [], "episodes": []}`,
+		"get_series_info:303": `{"episodes": [[{"id": "502", "episode_num": 2, "title": "Second"}, {"id": "501", "episode_num": 1, "title": "First"}],
+			[{"id": "503", "episode_num": 1, "title": "Third"}]]}`,
+		"get_series_info:304": `{"episodes": "unavailable"}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/player_api.php" || query.Get("username") != "user" || query.Get("password") != "pass" {
+			fmt.Fprint(w, `{"user_info": {"auth": 0}}`)
+			return
+		}
+		action := query.Get("action")
+		if action == "get_series_info" {
+			action += ":" + query.Get("series_id")
+		}
+		response, ok := responses[action]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+}
+
+func TestLogin(t *testing.T) {
+	server := newFakeServer(t)
+	defer server.Close()
+	account, err := NewClient(server.URL, "user", "pass").Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.UserInfo.Status != "Active" || account.UserInfo.MaxConnections.Int() != 1 || account.ServerInfo.Port != "8080" {
+		t.Errorf("Unexpected account %+v", account)
+	}
+	if _, err := NewClient(server.URL, "user", "wrong").Login(); err == nil {
+		t.Error("Expected error for unauthorized login")
+	}
+}
+
+func TestPlaylist(t *testing.T) {
+	server := newFakeServer(t)
+	defer server.Close()
+	client := NewClient(server.URL+"/", "user", "pass")
+	channels, err := client.Playlist(PlaylistOptions{VOD: true, Series: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []m3uparser.Channel{
+		{
+			"title": "Kantipur TV", "logo": "http://example.com/k.png", "category": "News",
+			"url":        server.URL + "/live/user/pass/101.ts",
+			"tvg":        map[string]string{"id": "KantipurTV.np", "name": "Kantipur TV"},
+			"attributes": map[string]string{"xc-stream-id": "101", "tvg-chno": "1", "catchup": "xc", "catchup-days": "3"},
+		},
+		{
+			"title": "Sports 1", "category": "Sports", "url": server.URL + "/live/user/pass/102.ts",
+			"tvg":        map[string]string{"name": "Sports 1"},
+			"attributes": map[string]string{"xc-stream-id": "102", "tvg-chno": "2"},
+		},
+		{"title": "A Movie", "category": "Movies", "url": server.URL + "/movie/user/pass/201.mp4"},
+		{"title": "Pilot", "category": "Shows", "logo": "http://example.com/s.png", "url": server.URL + "/series/user/pass/401.mkv", "duration": float64(1800)},
+		{"title": "A Show S02E01", "category": "Shows", "logo": "http://example.com/s.png", "url": server.URL + "/series/user/pass/402.mkv"},
+		{"title": "First", "category": "Shows", "url": server.URL + "/series/user/pass/501"},
+		{"title": "Second", "category": "Shows", "url": server.URL + "/series/user/pass/502"},
+		{"title": "Third", "category": "Shows", "url": server.URL + "/series/user/pass/503"},
+	}
+	if !reflect.DeepEqual(channels, expected) {
+		t.Errorf("Expected %v, got %v", expected, channels)
+	}
+
+	episodes, err := client.Episodes("303")
+	if err != nil || len(episodes) != 3 || episodes[2].Season.Int() != 2 {
+		t.Errorf("Expected 3 episodes with seasons from the season arrays, got %+v %v", episodes, err)
+	}
+	if _, err := client.Episodes("304"); err == nil {
+		t.Error("Expected error for invalid episodes")
+	}
+
+	start := time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	if _, err := m3uparser.CatchupURL(channels[0], start, time.Hour); err == nil {
+		t.Error("Expected error for start outside the catch-up days")
+	}
+	catchupURL, err := m3uparser.CatchupURL(channels[0], time.Now().Add(-time.Hour).UTC(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectedPrefix := server.URL + "/timeshift/user/pass/60/"; catchupURL[:len(expectedPrefix)] != expectedPrefix {
+		t.Errorf("Expected xc catch-up url, got %s", catchupURL)
+	}
+
+	parser := m3uparser.M3uParser{}
+	parser.Load(channels, false)
+	if err := parser.Where(`category == "News"`); err != nil || len(parser.GetStreamsSlice()) != 1 {
+		t.Errorf("Expected loaded channels to be filtered, got %v %v", err, parser.GetStreamsSlice())
+	}
+}
@@ -0,0 +1,296 @@
+package xtream
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+// ServerConfig - Configuration of a Server.
+type ServerConfig struct {
+	// Users maps usernames to passwords of the accounts allowed to log in.
+	Users map[string]string
+	// Collection returns the channels served, eg. the Collection method of a server.Handler.
+	// It is called on every request so refreshed or filtered collections are picked up.
+	Collection func() m3uparser.Collection
+	// Timezone reported in server_info, defaults to UTC.
+	Timezone string
+}
+
+// Server - An http.Handler serving channels as an Xtream Codes server: player_api.php with
+// login, get_live_categories and get_live_streams, and the /live/{username}/{password}/{id}.ts
+// and /timeshift/... stream URLs redirecting to the channel URLs.
+//
+// Categories are the group-titles of the channels and epg_channel_id their tvg-id. Stream and
+// category ids are derived from the channel URL and category name, so they stay stable across
+// refreshes of the collection. URLs with colliding checksums get distinct ids, see streamIDs.
+type Server struct {
+	config ServerConfig
+}
+
+// NewServer returns a server of config.
+func NewServer(config ServerConfig) *Server {
+	return &Server{config: config}
+}
+
+var (
+	liveURLRegex      = regexp.MustCompile(`^/(?:live/)?([^/]+)/([^/]+)/(\d+)(?:\.\w+)?$`)
+	timeshiftURLRegex = regexp.MustCompile(`^/timeshift/([^/]+)/([^/]+)/(\d+)/(\d{4}-\d{2}-\d{2}:\d{2}-\d{2})/(\d+)(?:\.\w+)?$`)
+)
+
+// authorized reports whether username and password are of a configured user.
+func (s *Server) authorized(username, password string) bool {
+	expected, ok := s.config.Users[username]
+	return ok && username != "" && expected == password
+}
+
+// liveStream - A channel of the collection with its ids.
+type liveStream struct {
+	channel    m3uparser.Channel
+	id         int
+	categoryID string
+}
+
+// checksumID returns a positive id derived from value.
+func checksumID(value string) int {
+	return int(crc32.ChecksumIEEE([]byte(value)) & 0x7fffffff)
+}
+
+// streamIDs returns the stream ids of urls. An id is the checksum of the URL. Of URLs with the
+// same checksum the smallest keeps it and the others take the checksum of the URL with a numbered
+// suffix, so ids don't depend on the channel order and adding or removing a URL only changes the
+// ids of the URLs its checksum collides with.
+func streamIDs(urls []string) map[string]int {
+	sorted := append([]string(nil), urls...)
+	sort.Strings(sorted)
+	ids := make(map[string]int, len(sorted))
+	taken := make(map[int]bool, len(sorted))
+	var colliding []string
+	for _, streamURL := range sorted {
+		id := checksumID(streamURL)
+		if taken[id] || id == 0 {
+			colliding = append(colliding, streamURL)
+			continue
+		}
+		taken[id] = true
+		ids[streamURL] = id
+	}
+	for _, streamURL := range colliding {
+		id := 0
+		for suffix := 1; taken[id] || id == 0; suffix++ {
+			id = checksumID(streamURL + "#" + strconv.Itoa(suffix))
+		}
+		taken[id] = true
+		ids[streamURL] = id
+	}
+	return ids
+}
+
+// streams returns the channels with their stream ids. Channels with the same URL share an id and
+// the first one is served.
+func (s *Server) streams() []liveStream {
+	if s.config.Collection == nil {
+		return nil
+	}
+	var channels []m3uparser.Channel
+	var urls []string
+	seen := make(map[string]bool)
+	for _, channel := range s.config.Collection().Channels() {
+		streamURL, _ := channel["url"].(string)
+		if streamURL == "" || seen[streamURL] {
+			continue
+		}
+		seen[streamURL] = true
+		channels = append(channels, channel)
+		urls = append(urls, streamURL)
+	}
+	ids := streamIDs(urls)
+	streams := make([]liveStream, 0, len(channels))
+	for i, channel := range channels {
+		category, _ := channel["category"].(string)
+		stream := liveStream{channel: channel, id: ids[urls[i]], categoryID: "0"}
+		if category != "" {
+			stream.categoryID = strconv.Itoa(checksumID(category))
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// find returns the channel of stream id.
+func (s *Server) find(id int) (m3uparser.Channel, bool) {
+	for _, stream := range s.streams() {
+		if stream.id == id {
+			return stream.channel, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) account(r *http.Request, username, password string) Account {
+	now := time.Now()
+	timezone := s.config.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	host, port := r.Host, "80"
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		host, port = r.Host[:i], r.Host[i+1:]
+	}
+	protocol := "http"
+	if r.TLS != nil {
+		protocol = "https"
+	}
+	return Account{
+		UserInfo: UserInfo{
+			Username: username, Password: password, Auth: "1", Status: "Active",
+			IsTrial: "0", ActiveCons: "0", MaxConnections: "1",
+			AllowedOutputFormats: []string{"ts", "m3u8"},
+		},
+		ServerInfo: ServerInfo{
+			URL: host, Port: Value(port), ServerProtocol: protocol, Timezone: timezone,
+			TimestampNow: Value(strconv.FormatInt(now.Unix(), 10)), TimeNow: now.UTC().Format("2006-01-02 15:04:05"),
+		},
+	}
+}
+
+// categories returns the live categories in order of first appearance.
+func categories(streams []liveStream) []Category {
+	categories := []Category{}
+	seen := make(map[string]bool)
+	for _, stream := range streams {
+		if seen[stream.categoryID] {
+			continue
+		}
+		seen[stream.categoryID] = true
+		name, _ := stream.channel["category"].(string)
+		if name == "" {
+			name = "Uncategorized"
+		}
+		categories = append(categories, Category{CategoryID: Value(stream.categoryID), CategoryName: name, ParentID: "0"})
+	}
+	return categories
+}
+
+// liveStreams returns the streams of categoryID, all streams for an empty categoryID.
+func liveStreams(streams []liveStream, categoryID string) []Stream {
+	result := []Stream{}
+	for i, stream := range streams {
+		if categoryID != "" && stream.categoryID != categoryID {
+			continue
+		}
+		channel := stream.channel
+		title, _ := channel["title"].(string)
+		logo, _ := channel["logo"].(string)
+		tvg, _ := channel["tvg"].(map[string]string)
+		attributes, _ := channel["attributes"].(map[string]string)
+		num := strconv.Itoa(i + 1)
+		if chno := attributes["tvg-chno"]; chno != "" {
+			num = chno
+		}
+		item := Stream{
+			Num: Value(num), Name: title, StreamType: "live", StreamID: Value(strconv.Itoa(stream.id)),
+			StreamIcon: logo, EPGChannelID: tvg["id"], CategoryID: Value(stream.categoryID), TVArchive: "0",
+		}
+		if catchup, ok := m3uparser.ChannelCatchup(channel); ok {
+			item.TVArchive = "1"
+			item.TVArchiveDuration = Value(strconv.Itoa(catchup.Days))
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// playerAPI serves player_api.php. Unauthorized requests get the auth 0 response of Xtream Codes.
+func (s *Server) playerAPI(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	username, password := query.Get("username"), query.Get("password")
+	w.Header().Set("Content-Type", "application/json")
+	if !s.authorized(username, password) {
+		w.Write([]byte(`{"user_info":{"auth":0}}`))
+		return
+	}
+	var response interface{}
+	switch query.Get("action") {
+	case "":
+		response = s.account(r, username, password)
+	case "get_live_categories":
+		response = categories(s.streams())
+	case "get_live_streams":
+		response = liveStreams(s.streams(), query.Get("category_id"))
+	case "get_vod_categories", "get_vod_streams", "get_series_categories", "get_series":
+		response = []interface{}{}
+	default:
+		http.Error(w, `{"error":"unsupported action"}`, http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// ServeHTTP serves player_api.php and redirects stream URLs to the channels.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/player_api.php" {
+		s.playerAPI(w, r)
+		return
+	}
+	if match := timeshiftURLRegex.FindStringSubmatch(r.URL.Path); match != nil {
+		s.timeshift(w, r, match)
+		return
+	}
+	match := liveURLRegex.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.authorized(match[1], match[2]) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, _ := strconv.Atoi(match[3])
+	channel, ok := s.find(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, channel["url"].(string), http.StatusFound)
+}
+
+// timeshift redirects /timeshift/{username}/{password}/{minutes}/{Y-m-d:H-M}/{id}.ts to the
+// archive URL of the channel, see m3uparser.CatchupURL. The start is in the server timezone.
+func (s *Server) timeshift(w http.ResponseWriter, r *http.Request, match []string) {
+	if !s.authorized(match[1], match[2]) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	location := time.UTC
+	if s.config.Timezone != "" {
+		if loaded, err := time.LoadLocation(s.config.Timezone); err == nil {
+			location = loaded
+		}
+	}
+	start, err := time.ParseInLocation("2006-01-02:15-04", match[4], location)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	minutes, _ := strconv.Atoi(match[3])
+	id, _ := strconv.Atoi(match[5])
+	channel, ok := s.find(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	archiveURL, err := m3uparser.CatchupURL(channel, start, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, archiveURL, http.StatusFound)
+}
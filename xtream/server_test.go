@@ -0,0 +1,164 @@
+package xtream
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pawanpaudel93/go-m3u-parser/m3uparser"
+)
+
+func TestServer(t *testing.T) {
+	parser := m3uparser.M3uParser{}
+	parser.ParseSources([]string{`#EXTM3U
+#EXTINF:-1 tvg-id="KantipurTV.np" tvg-chno="7" tvg-logo="http://example.com/k.png" group-title="News" catchup="shift" catchup-days="2",Kantipur TV
+http://example.com/kantipur.m3u8
+#EXTINF:-1 tvg-id="Sports1.np" group-title="Sports",Sports 1
+http://example.com/sports.m3u8
+#EXTINF:-1,No Group
+http://example.com/other.m3u8
+`}, false, false)
+	collection := parser.Collection()
+	ts := httptest.NewServer(NewServer(ServerConfig{
+		Users:      map[string]string{"user": "pass"},
+		Collection: func() m3uparser.Collection { return collection },
+	}))
+	defer ts.Close()
+
+	if _, err := NewClient(ts.URL, "user", "wrong").Login(); err == nil {
+		t.Error("login with wrong password succeeded")
+	}
+	client := NewClient(ts.URL, "user", "pass")
+	client.HTTPClient.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	account, err := client.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.UserInfo.Username != "user" || account.ServerInfo.Timezone != "UTC" {
+		t.Errorf("account %+v", account)
+	}
+	categories, err := client.LiveCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, category := range categories {
+		names = append(names, category.CategoryName)
+	}
+	if !reflect.DeepEqual(names, []string{"News", "Sports", "Uncategorized"}) {
+		t.Errorf("categories %v", names)
+	}
+	streams, err := client.LiveStreams(string(categories[0].CategoryID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streams) != 1 || streams[0].Name != "Kantipur TV" || streams[0].EPGChannelID != "KantipurTV.np" ||
+		streams[0].Num != "7" || streams[0].TVArchive != "1" || streams[0].TVArchiveDuration != "2" {
+		t.Fatalf("streams %+v", streams)
+	}
+
+	// the playlist read back from the server redirects to the original streams
+	channels, err := client.Playlist(PlaylistOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 3 {
+		t.Fatalf("%d channels, want 3", len(channels))
+	}
+	for i, channel := range channels {
+		resp, err := client.HTTPClient.Get(channel["url"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if want := collection.At(i)["url"]; resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != want {
+			t.Errorf("%s: status %d location %q, want %q", channel["url"], resp.StatusCode, resp.Header.Get("Location"), want)
+		}
+	}
+
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	archive := fmt.Sprintf("%s/timeshift/user/pass/30/%s/%s.ts", ts.URL, start.Format("2006-01-02:15-04"), streams[0].StreamID)
+	resp, err := client.HTTPClient.Get(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := fmt.Sprintf("http://example.com/kantipur.m3u8?utc=%d&lutc=", start.Unix()); resp.StatusCode != http.StatusFound ||
+		!strings.HasPrefix(resp.Header.Get("Location"), want) {
+		t.Errorf("timeshift: status %d location %q, want prefix %q", resp.StatusCode, resp.Header.Get("Location"), want)
+	}
+
+	resp, err = client.HTTPClient.Get(ts.URL + "/live/user/wrong/" + string(streams[0].StreamID) + ".ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong password: status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestServerStreamIDCollision(t *testing.T) {
+	// the checksums of these urls collide
+	urls := []string{"http://example.com/883918.m3u8", "http://example.com/13060404.m3u8"}
+	if checksumID(urls[0]) != checksumID(urls[1]) {
+		t.Fatal("Expected the checksums to collide")
+	}
+	var ids [2]map[string]string
+	for i, order := range [][]string{urls, {urls[1], urls[0]}} {
+		collection := m3uparser.NewCollection([]m3uparser.Channel{
+			{"title": "A", "url": order[0]},
+			{"title": "B", "url": order[1]},
+		})
+		ts := httptest.NewServer(NewServer(ServerConfig{
+			Users:      map[string]string{"user": "pass"},
+			Collection: func() m3uparser.Collection { return collection },
+		}))
+		client := NewClient(ts.URL, "user", "pass")
+		client.HTTPClient.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		streams, err := client.LiveStreams("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(streams) != 2 || streams[0].StreamID == streams[1].StreamID {
+			t.Fatalf("Expected 2 streams with distinct ids, got %+v", streams)
+		}
+		ids[i] = make(map[string]string)
+		for j, stream := range streams {
+			resp, err := client.HTTPClient.Get(client.StreamURL("live", string(stream.StreamID), "ts"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if location := resp.Header.Get("Location"); location != order[j] {
+				t.Errorf("Stream %s redirects to %q, want %q", stream.StreamID, location, order[j])
+			}
+			ids[i][order[j]] = string(stream.StreamID)
+		}
+		ts.Close()
+	}
+	if !reflect.DeepEqual(ids[0], ids[1]) {
+		t.Errorf("Stream ids depend on the channel order: %v and %v", ids[0], ids[1])
+	}
+}
+
+func TestStreamIDsStable(t *testing.T) {
+	// the checksums of the first two urls collide
+	urls := []string{"http://example.com/883918.m3u8", "http://example.com/13060404.m3u8", "http://example.com/news.m3u8"}
+	before := streamIDs([]string{urls[0], urls[2]})
+	after := streamIDs(urls)
+	if before[urls[2]] != after[urls[2]] || after[urls[2]] != checksumID(urls[2]) {
+		t.Errorf("Adding a colliding url changed the id of another url: %d and %d", before[urls[2]], after[urls[2]])
+	}
+	// the smallest of the colliding urls keeps the checksum
+	if after[urls[1]] != checksumID(urls[1]) || after[urls[0]] == after[urls[1]] || after[urls[0]] == 0 {
+		t.Errorf("Colliding ids %v", after)
+	}
+	if again := streamIDs([]string{urls[2], urls[1], urls[0]}); !reflect.DeepEqual(again, after) {
+		t.Errorf("Stream ids depend on the url order: %v and %v", again, after)
+	}
+}